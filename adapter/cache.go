@@ -2,7 +2,9 @@ package adapter
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
 	"time"
 
 	"github.com/sagernet/sing/common/varbin"
@@ -13,17 +15,50 @@ type Cache interface {
 	Close() error
 	LoadBinary(tag string) (*SavedBinary, error)
 	SaveBinary(tag string, binary *SavedBinary) error
+	DeleteBinary(tag string) error
+	ListBinary(prefix string) ([]string, error)
+}
+
+// PurgeBinary deletes all cached binaries with the tag prefix and returns the number of deleted binaries.
+func PurgeBinary(cache Cache, prefix string) (int, error) {
+	tags, err := cache.ListBinary(prefix)
+	if err != nil {
+		return 0, err
+	}
+	for index, tag := range tags {
+		err = cache.DeleteBinary(tag)
+		if err != nil {
+			return index, err
+		}
+	}
+	return len(tags), nil
 }
 
 type SavedBinary struct {
-	Content     []byte
-	LastUpdated time.Time
-	LastEtag    string
+	Content      []byte
+	LastUpdated  time.Time
+	LastEtag     string
+	ContentEtag  string
+	LastModified time.Time
+	ExcludeEtag  string
+	Diagnostics  []Diagnostic
+	Encoded      []EncodedContent
+}
+
+// EncodedContent is Content compressed with Encoding, served to clients accepting the encoding.
+type EncodedContent struct {
+	Encoding string
+	Content  []byte
+}
+
+func ContentEtag(content []byte) string {
+	contentHash := sha256.Sum256(content)
+	return "\"" + hex.EncodeToString(contentHash[:16]) + "\""
 }
 
 func (s *SavedBinary) MarshalBinary() ([]byte, error) {
 	var buffer bytes.Buffer
-	err := binary.Write(&buffer, binary.BigEndian, uint8(1))
+	err := binary.Write(&buffer, binary.BigEndian, uint8(5))
 	if err != nil {
 		return nil, err
 	}
@@ -39,6 +74,26 @@ func (s *SavedBinary) MarshalBinary() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	err = varbin.Write(&buffer, binary.BigEndian, s.ContentEtag)
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Write(&buffer, binary.BigEndian, s.LastModified.Unix())
+	if err != nil {
+		return nil, err
+	}
+	err = varbin.Write(&buffer, binary.BigEndian, s.ExcludeEtag)
+	if err != nil {
+		return nil, err
+	}
+	err = varbin.Write(&buffer, binary.BigEndian, s.Diagnostics)
+	if err != nil {
+		return nil, err
+	}
+	err = varbin.Write(&buffer, binary.BigEndian, s.Encoded)
+	if err != nil {
+		return nil, err
+	}
 	return buffer.Bytes(), nil
 }
 
@@ -63,5 +118,39 @@ func (s *SavedBinary) UnmarshalBinary(data []byte) error {
 	if err != nil {
 		return err
 	}
+	if version < 2 {
+		return nil
+	}
+	err = varbin.Read(reader, binary.BigEndian, &s.ContentEtag)
+	if err != nil {
+		return err
+	}
+	var lastModified int64
+	err = binary.Read(reader, binary.BigEndian, &lastModified)
+	if err != nil {
+		return err
+	}
+	s.LastModified = time.Unix(lastModified, 0)
+	if version < 3 {
+		return nil
+	}
+	err = varbin.Read(reader, binary.BigEndian, &s.ExcludeEtag)
+	if err != nil {
+		return err
+	}
+	if version < 4 {
+		return nil
+	}
+	err = varbin.Read(reader, binary.BigEndian, &s.Diagnostics)
+	if err != nil {
+		return err
+	}
+	if version < 5 {
+		return nil
+	}
+	err = varbin.Read(reader, binary.BigEndian, &s.Encoded)
+	if err != nil {
+		return err
+	}
 	return nil
 }
@@ -3,6 +3,8 @@ package adapter
 import (
 	"context"
 
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
 	C "github.com/sagernet/srsc/constant"
 	"github.com/sagernet/srsc/option"
 )
@@ -15,6 +17,27 @@ type Convertor interface {
 }
 
 type ConvertOptions struct {
-	Options  option.ConvertOptions
-	Metadata C.Metadata
+	Options     option.ConvertOptions
+	Metadata    C.Metadata
+	Diagnostics *Diagnostics
+}
+
+// DropLine records a source line dropped during conversion,
+// an error with the line is returned instead in strict mode.
+func (o ConvertOptions) DropLine(line string, reason ...any) error {
+	if o.Options.Strict {
+		return E.New("strict mode: dropped line `", line, "`: ", F.ToString(reason...))
+	}
+	o.Diagnostics.DropLine(line, reason...)
+	return nil
+}
+
+// DropRule records a rule dropped during conversion,
+// an error with the rule is returned instead in strict mode.
+func (o ConvertOptions) DropRule(rule Rule, reason ...any) error {
+	if o.Options.Strict {
+		return E.New("strict mode: dropped rule ", ruleContent(rule), ": ", F.ToString(reason...))
+	}
+	o.Diagnostics.DropRule(rule, reason...)
+	return nil
 }
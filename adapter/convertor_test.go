@@ -0,0 +1,77 @@
+package adapter
+
+import (
+	"testing"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/srsc/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertOptionsDrop(t *testing.T) {
+	t.Parallel()
+	rule := Rule{Type: C.RuleTypeDefault}
+	rule.DefaultOptions.ProcessName = []string{"curl"}
+	for _, testCase := range []struct {
+		name     string
+		strict   bool
+		drop     func(options ConvertOptions) error
+		entries  []Diagnostic
+		errorMsg string
+	}{
+		{
+			name: "line",
+			drop: func(options ConvertOptions) error {
+				return options.DropLine("UNKNOWN,a.com", "unsupported rule type: ", "UNKNOWN")
+			},
+			entries: []Diagnostic{{Type: DiagnosticTypeLine, Content: "UNKNOWN,a.com", Reason: "unsupported rule type: UNKNOWN"}},
+		},
+		{
+			name: "rule",
+			drop: func(options ConvertOptions) error {
+				return options.DropRule(rule, "unsupported by hosts file")
+			},
+			entries: []Diagnostic{{Type: DiagnosticTypeRule, Content: `{"process_name": "curl"}`, Reason: "unsupported by hosts file"}},
+		},
+		{
+			name:   "strict line",
+			strict: true,
+			drop: func(options ConvertOptions) error {
+				return options.DropLine("UNKNOWN,a.com", "unsupported rule type: ", "UNKNOWN")
+			},
+			errorMsg: "strict mode: dropped line `UNKNOWN,a.com`: unsupported rule type: UNKNOWN",
+		},
+		{
+			name:   "strict rule",
+			strict: true,
+			drop: func(options ConvertOptions) error {
+				return options.DropRule(rule, "unsupported by hosts file")
+			},
+			errorMsg: `strict mode: dropped rule {"process_name": "curl"}: unsupported by hosts file`,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			diagnostics := NewDiagnostics()
+			err := testCase.drop(ConvertOptions{
+				Options: option.ConvertOptions{
+					TargetConvertOptions: option.TargetConvertOptions{Strict: testCase.strict},
+				},
+				Diagnostics: diagnostics,
+			})
+			if testCase.errorMsg != "" {
+				require.EqualError(t, err, testCase.errorMsg)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, testCase.entries, diagnostics.Entries())
+		})
+	}
+}
+
+func TestConvertOptionsDropWithoutDiagnostics(t *testing.T) {
+	t.Parallel()
+	require.NoError(t, ConvertOptions{}.DropLine("UNKNOWN,a.com", "unsupported"))
+	require.NoError(t, ConvertOptions{}.DropRule(Rule{Type: C.RuleTypeDefault}, "unsupported"))
+}
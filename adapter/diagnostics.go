@@ -0,0 +1,127 @@
+package adapter
+
+import (
+	"sync"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/json/badjson"
+	"github.com/sagernet/sing/common/ranges"
+)
+
+const (
+	DiagnosticTypeLine = "line"
+	DiagnosticTypeRule = "rule"
+)
+
+type Diagnostic struct {
+	Type    string `json:"type"`
+	Content string `json:"content"`
+	Reason  string `json:"reason"`
+}
+
+// Diagnostics collects source lines and rules dropped during conversion.
+//
+// Methods are safe to call on a nil Diagnostics, records are discarded in that case.
+type Diagnostics struct {
+	access  sync.Mutex
+	entries []Diagnostic
+}
+
+func NewDiagnostics() *Diagnostics {
+	return &Diagnostics{}
+}
+
+func (d *Diagnostics) DropLine(line string, reason ...any) {
+	if d == nil {
+		return
+	}
+	d.add(Diagnostic{
+		Type:    DiagnosticTypeLine,
+		Content: line,
+		Reason:  F.ToString(reason...),
+	})
+}
+
+func (d *Diagnostics) DropRule(rule Rule, reason ...any) {
+	if d == nil {
+		return
+	}
+	d.add(Diagnostic{
+		Type:    DiagnosticTypeRule,
+		Content: ruleContent(rule),
+		Reason:  F.ToString(reason...),
+	})
+}
+
+func (d *Diagnostics) add(diagnostic Diagnostic) {
+	d.access.Lock()
+	defer d.access.Unlock()
+	d.entries = append(d.entries, diagnostic)
+}
+
+func (d *Diagnostics) Entries() []Diagnostic {
+	if d == nil {
+		return nil
+	}
+	d.access.Lock()
+	defer d.access.Unlock()
+	return append([]Diagnostic(nil), d.entries...)
+}
+
+type defaultRuleExtraItems struct {
+	GEOIP       []string `json:"geoip,omitempty"`
+	SourceGEOIP []string `json:"source_geoip,omitempty"`
+	IPASN       []string `json:"ip_asn,omitempty"`
+	SourceIPASN []string `json:"source_ip_asn,omitempty"`
+	GEOSite     []string `json:"geosite,omitempty"`
+	Inbound     []string `json:"inbound,omitempty"`
+	InboundType []string `json:"inbound_type,omitempty"`
+	InboundPort []string `json:"inbound_port,omitempty"`
+	InboundUser []string `json:"inbound_user,omitempty"`
+}
+
+func ruleContent(rule Rule) string {
+	content, err := marshalRule(rule)
+	if err != nil {
+		return F.ToString("<", err, ">")
+	}
+	return string(content)
+}
+
+// marshalRule marshals rule as a headless rule, with items not supported by headless rules for default rules.
+func marshalRule(rule Rule) ([]byte, error) {
+	if rule.Type != C.RuleTypeDefault {
+		return json.Marshal(rule.ToHeadless())
+	}
+	defaultRule := rule.DefaultOptions
+	return badjson.MarshallObjects(defaultRule.DefaultHeadlessRule, defaultRuleExtraItems{
+		GEOIP:       defaultRule.GEOIP,
+		SourceGEOIP: defaultRule.SourceGEOIP,
+		IPASN:       defaultRule.IPASN,
+		SourceIPASN: defaultRule.SourceIPASN,
+		GEOSite:     defaultRule.GEOSite,
+		Inbound:     defaultRule.Inbound,
+		InboundType: defaultRule.InboundType,
+		InboundPort: common.Map(defaultRule.InboundPort, func(it ranges.Range[uint16]) string {
+			return F.ToString(it.Start, ":", it.End)
+		}),
+		InboundUser: defaultRule.InboundUser,
+	})
+}
+
+// DiagnosticCount returns the number of dropped lines and dropped rules in entries.
+func DiagnosticCount(entries []Diagnostic) (int, int) {
+	var droppedLines, droppedRules int
+	for _, entry := range entries {
+		switch entry.Type {
+		case DiagnosticTypeLine:
+			droppedLines++
+		case DiagnosticTypeRule:
+			droppedRules++
+		}
+	}
+	return droppedLines, droppedRules
+}
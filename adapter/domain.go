@@ -0,0 +1,110 @@
+package adapter
+
+import (
+	"sort"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common"
+
+	"golang.org/x/net/idna"
+)
+
+// OptimizeDomain normalizes domain and domain suffix items of rules
+// (lowercase, punycode, without trailing dots), removes duplicates and items covered by a broader domain suffix,
+// and returns the number of removed items.
+func OptimizeDomain(rules []Rule) ([]Rule, int) {
+	var removed int
+	outputRules := make([]Rule, 0, len(rules))
+	for _, rule := range rules {
+		var ruleRemoved int
+		if rule.Type == C.RuleTypeDefault {
+			rule.DefaultOptions, ruleRemoved = optimizeDefaultRuleDomain(rule.DefaultOptions)
+		} else {
+			rule.LogicalOptions.Rules, ruleRemoved = OptimizeDomain(rule.LogicalOptions.Rules)
+		}
+		removed += ruleRemoved
+		outputRules = append(outputRules, rule)
+	}
+	return outputRules, removed
+}
+
+func optimizeDefaultRuleDomain(rule DefaultRule) (DefaultRule, int) {
+	if len(rule.Domain) == 0 && len(rule.DomainSuffix) == 0 {
+		return rule, 0
+	}
+	originLength := len(rule.Domain) + len(rule.DomainSuffix)
+	domainList := normalizeDomainList(rule.Domain, false)
+	domainSuffixList := normalizeDomainList(rule.DomainSuffix, true)
+	domainSuffixSet := newDomainSuffixSet(domainSuffixList)
+	rule.Domain = common.Filter(domainList, func(domain string) bool {
+		return !domainSuffixSet.match(domain)
+	})
+	rule.DomainSuffix = common.Filter(domainSuffixList, func(domainSuffix string) bool {
+		return !domainSuffixSet.covers(domainSuffix)
+	})
+	return rule, originLength - len(rule.Domain) - len(rule.DomainSuffix)
+}
+
+func normalizeDomainList(domainList []string, isSuffix bool) []string {
+	normalized := make([]string, 0, len(domainList))
+	for _, domain := range domainList {
+		domain = NormalizeDomain(domain, isSuffix)
+		if domain == "" || domain == "." {
+			continue
+		}
+		normalized = append(normalized, domain)
+	}
+	normalized = common.Uniq(normalized)
+	sort.Strings(normalized)
+	return normalized
+}
+
+// NormalizeDomain lowercases domain and converts it to ASCII,
+// the leading dot of domain suffixes is kept.
+func NormalizeDomain(domain string, isSuffix bool) string {
+	domain = strings.TrimRight(strings.ToLower(strings.TrimSpace(domain)), ".")
+	var prefix string
+	if isSuffix && strings.HasPrefix(domain, ".") {
+		prefix = "."
+		domain = domain[1:]
+	}
+	asciiDomain, err := idna.Lookup.ToASCII(domain)
+	if err == nil {
+		domain = asciiDomain
+	}
+	return prefix + domain
+}
+
+// domainSuffixSet matches domains with sing-box domain suffix semantics:
+// `example.com` matches the domain and all its subdomains, `.example.com` matches only subdomains.
+type domainSuffixSet map[string]bool
+
+func newDomainSuffixSet(domainSuffixList []string) domainSuffixSet {
+	set := make(domainSuffixSet, len(domainSuffixList))
+	for _, domainSuffix := range domainSuffixList {
+		set[domainSuffix] = true
+	}
+	return set
+}
+
+func (s domainSuffixSet) match(domain string) bool {
+	return s[domain] || s.matchParent(domain)
+}
+
+// covers reports whether all domains matched by domainSuffix are matched by another suffix in the set.
+func (s domainSuffixSet) covers(domainSuffix string) bool {
+	if strings.HasPrefix(domainSuffix, ".") {
+		return s[domainSuffix[1:]] || s.matchParent(domainSuffix[1:])
+	}
+	return s.matchParent(domainSuffix)
+}
+
+func (s domainSuffixSet) matchParent(domain string) bool {
+	for i := 0; i < len(domain); i++ {
+		if domain[i] == '.' && (s[domain[i:]] || s[domain[i+1:]]) {
+			return true
+		}
+	}
+	return false
+}
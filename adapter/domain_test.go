@@ -0,0 +1,67 @@
+package adapter
+
+import (
+	"testing"
+
+	C "github.com/sagernet/sing-box/constant"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptimizeDomain(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name     string
+		rules    []Rule
+		expected []Rule
+		removed  int
+	}{
+		{
+			name:     "normalize",
+			rules:    []Rule{domainRule([]string{"Example.COM.", " www.example.org "}, []string{"Example.NET"})},
+			expected: []Rule{domainRule([]string{"example.com", "www.example.org"}, []string{"example.net"})},
+		},
+		{
+			name:     "punycode",
+			rules:    []Rule{domainRule([]string{"例子.测试"}, []string{".Bücher.example"})},
+			expected: []Rule{domainRule([]string{"xn--fsqu00a.xn--0zwm56d"}, []string{".xn--bcher-kva.example"})},
+		},
+		{
+			name:     "duplicate",
+			rules:    []Rule{domainRule([]string{"b.com", "a.com", "A.com"}, []string{"c.com", "c.com."})},
+			expected: []Rule{domainRule([]string{"a.com", "b.com"}, []string{"c.com"})},
+			removed:  2,
+		},
+		{
+			name:     "covered by domain suffix",
+			rules:    []Rule{domainRule([]string{"a.com", "www.a.com", "ba.com"}, []string{"a.com", "x.a.com", ".a.com"})},
+			expected: []Rule{domainRule([]string{"ba.com"}, []string{"a.com"})},
+			removed:  4,
+		},
+		{
+			name:     "dot domain suffix keeps domain",
+			rules:    []Rule{domainRule([]string{"a.com", "www.a.com"}, []string{".a.com", "x.a.com"})},
+			expected: []Rule{domainRule([]string{"a.com"}, []string{".a.com"})},
+			removed:  2,
+		},
+		{
+			name: "logical",
+			rules: []Rule{logicalRule(C.LogicalTypeAnd, false,
+				domainRule([]string{"www.a.com"}, []string{"a.com"}),
+				invertRule(domainRule([]string{"B.com"}, nil)),
+			)},
+			expected: []Rule{logicalRule(C.LogicalTypeAnd, false,
+				domainRule(nil, []string{"a.com"}),
+				invertRule(domainRule([]string{"b.com"}, nil)),
+			)},
+			removed: 1,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			rules, removed := OptimizeDomain(testCase.rules)
+			require.Equal(t, testCase.expected, rules)
+			require.Equal(t, testCase.removed, removed)
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package adapter
+
+import "net/http"
+
+type Endpoint interface {
+	http.Handler
+	Start() error
+	Close() error
+}
@@ -0,0 +1,158 @@
+package adapter
+
+import (
+	"net/netip"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common"
+
+	"go4.org/netipx"
+)
+
+// ExcludeRules removes domains, domain suffixes and IP CIDRs listed in excludeRules from rules.
+//
+// Items of default rules and sub-rules of logical rules are excluded, inverted rules are kept unchanged since
+// removing items from them would match more. A rule is dropped when all its address items are excluded,
+// a logical rule is dropped when a sub-rule of an `and` rule or all sub-rules of an `or` rule are dropped.
+// A domain suffix excludes the domain itself and all its subdomains,
+// a domain excludes only the same domain, domains are compared case-insensitively, CIDRs are split as needed.
+func ExcludeRules(rules []Rule, excludeRules []Rule) []Rule {
+	var excluder ruleExcluder
+	excluder.domain = make(map[string]bool)
+	excluder.domainSuffix = make(domainSuffixSet)
+	var excludeBuilder netipx.IPSetBuilder
+	for _, rule := range excludeRules {
+		excluder.add(rule, &excludeBuilder)
+	}
+	excludeSet, _ := excludeBuilder.IPSet()
+	if excludeSet != nil && len(excludeSet.Ranges()) > 0 {
+		excluder.ipSet = excludeSet
+	}
+	if len(excluder.domain) == 0 && len(excluder.domainSuffix) == 0 && excluder.ipSet == nil {
+		return rules
+	}
+	var outputRules []Rule
+	for _, rule := range rules {
+		rule, loaded := excluder.excludeRule(rule)
+		if !loaded {
+			continue
+		}
+		outputRules = append(outputRules, rule)
+	}
+	return outputRules
+}
+
+type ruleExcluder struct {
+	domain       map[string]bool
+	domainSuffix domainSuffixSet
+	ipSet        *netipx.IPSet
+}
+
+func (e *ruleExcluder) add(rule Rule, ipSetBuilder *netipx.IPSetBuilder) {
+	if rule.Type != C.RuleTypeDefault {
+		for _, subRule := range rule.LogicalOptions.Rules {
+			e.add(subRule, ipSetBuilder)
+		}
+		return
+	}
+	for _, domain := range rule.DefaultOptions.Domain {
+		e.domain[NormalizeDomain(domain, false)] = true
+	}
+	for _, domainSuffix := range rule.DefaultOptions.DomainSuffix {
+		e.domainSuffix[NormalizeDomain(domainSuffix, true)] = true
+	}
+	for _, ipCIDR := range rule.DefaultOptions.IPCIDR {
+		prefix, err := parseIPCIDR(ipCIDR)
+		if err == nil {
+			ipSetBuilder.AddPrefix(prefix)
+		}
+	}
+}
+
+func (e *ruleExcluder) excludeRule(rule Rule) (Rule, bool) {
+	if rule.Type == C.RuleTypeDefault {
+		if rule.DefaultOptions.Invert {
+			return rule, true
+		}
+		defaultRule, loaded := e.exclude(rule.DefaultOptions)
+		if !loaded {
+			return Rule{}, false
+		}
+		rule.DefaultOptions = defaultRule
+		return rule, true
+	}
+	if rule.LogicalOptions.Invert {
+		return rule, true
+	}
+	subRules := make([]Rule, 0, len(rule.LogicalOptions.Rules))
+	for _, subRule := range rule.LogicalOptions.Rules {
+		subRule, loaded := e.excludeRule(subRule)
+		if !loaded {
+			if rule.LogicalOptions.Mode == C.LogicalTypeAnd {
+				return Rule{}, false
+			}
+			continue
+		}
+		subRules = append(subRules, subRule)
+	}
+	if len(subRules) == 0 {
+		return Rule{}, false
+	}
+	rule.LogicalOptions.Rules = subRules
+	return rule, true
+}
+
+func (e *ruleExcluder) exclude(rule DefaultRule) (DefaultRule, bool) {
+	hadAddress := hasDestinationAddress(rule)
+	rule.Domain = common.Filter(rule.Domain, func(domain string) bool {
+		domain = NormalizeDomain(domain, false)
+		return !e.domain[domain] && !e.domainSuffix.match(domain)
+	})
+	rule.DomainSuffix = common.Filter(rule.DomainSuffix, func(domainSuffix string) bool {
+		domainSuffix = NormalizeDomain(domainSuffix, true)
+		if strings.HasPrefix(domainSuffix, ".") {
+			return !e.domainSuffix[domainSuffix] && !e.domainSuffix.match(domainSuffix[1:])
+		}
+		return !e.domainSuffix.match(domainSuffix)
+	})
+	if e.ipSet != nil {
+		var ipCIDRs []string
+		for _, ipCIDR := range rule.IPCIDR {
+			prefix, err := parseIPCIDR(ipCIDR)
+			if err != nil || !e.ipSet.OverlapsPrefix(prefix) {
+				ipCIDRs = append(ipCIDRs, ipCIDR)
+				continue
+			}
+			var builder netipx.IPSetBuilder
+			builder.AddPrefix(prefix)
+			builder.RemoveSet(e.ipSet)
+			remainSet, _ := builder.IPSet()
+			if remainSet != nil {
+				ipCIDRs = append(ipCIDRs, common.Map(remainSet.Prefixes(), netip.Prefix.String)...)
+			}
+		}
+		rule.IPCIDR = ipCIDRs
+	}
+	if hadAddress && !hasDestinationAddress(rule) {
+		return DefaultRule{}, false
+	}
+	return rule, true
+}
+
+func hasDestinationAddress(rule DefaultRule) bool {
+	return len(rule.Domain) > 0 || len(rule.DomainSuffix) > 0 || len(rule.DomainKeyword) > 0 || len(rule.DomainRegex) > 0 ||
+		len(rule.IPCIDR) > 0 || len(rule.GEOIP) > 0 || len(rule.IPASN) > 0 || len(rule.GEOSite) > 0
+}
+
+func parseIPCIDR(ipCIDR string) (netip.Prefix, error) {
+	prefix, err := netip.ParsePrefix(ipCIDR)
+	if err == nil {
+		return prefix.Masked(), nil
+	}
+	address, addrErr := netip.ParseAddr(ipCIDR)
+	if addrErr != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(address, address.BitLen()), nil
+}
@@ -0,0 +1,145 @@
+package adapter
+
+import (
+	"testing"
+
+	C "github.com/sagernet/sing-box/constant"
+	boxOption "github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func domainRule(domain []string, domainSuffix []string) Rule {
+	return Rule{
+		Type: C.RuleTypeDefault,
+		DefaultOptions: DefaultRule{
+			DefaultHeadlessRule: boxOption.DefaultHeadlessRule{
+				Domain:       domain,
+				DomainSuffix: domainSuffix,
+			},
+		},
+	}
+}
+
+func ipCIDRRule(ipCIDR ...string) Rule {
+	return Rule{
+		Type: C.RuleTypeDefault,
+		DefaultOptions: DefaultRule{
+			DefaultHeadlessRule: boxOption.DefaultHeadlessRule{
+				IPCIDR: ipCIDR,
+			},
+		},
+	}
+}
+
+func logicalRule(mode string, invert bool, rules ...Rule) Rule {
+	return Rule{
+		Type: C.RuleTypeLogical,
+		LogicalOptions: LogicalRule{
+			Mode:   mode,
+			Rules:  rules,
+			Invert: invert,
+		},
+	}
+}
+
+func invertRule(rule Rule) Rule {
+	rule.DefaultOptions.Invert = true
+	return rule
+}
+
+func TestExcludeRules(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name     string
+		rules    []Rule
+		excludes []Rule
+		expected []Rule
+	}{
+		{
+			name:     "domain",
+			rules:    []Rule{domainRule([]string{"a.com", "b.com"}, nil)},
+			excludes: []Rule{domainRule([]string{"a.com"}, nil)},
+			expected: []Rule{domainRule([]string{"b.com"}, nil)},
+		},
+		{
+			name:     "domain only excludes same domain",
+			rules:    []Rule{domainRule([]string{"www.a.com"}, []string{"a.com"})},
+			excludes: []Rule{domainRule([]string{"a.com"}, nil)},
+			expected: []Rule{domainRule([]string{"www.a.com"}, []string{"a.com"})},
+		},
+		{
+			name:     "domain suffix",
+			rules:    []Rule{domainRule([]string{"a.com", "www.a.com", "ba.com"}, []string{"x.a.com", ".a.com", "b.com"})},
+			excludes: []Rule{domainRule(nil, []string{"a.com"})},
+			expected: []Rule{domainRule([]string{"ba.com"}, []string{"b.com"})},
+		},
+		{
+			name:     "dot domain suffix keeps domain",
+			rules:    []Rule{domainRule([]string{"a.com", "www.a.com"}, []string{"a.com"})},
+			excludes: []Rule{domainRule(nil, []string{".a.com"})},
+			expected: []Rule{domainRule([]string{"a.com"}, []string{"a.com"})},
+		},
+		{
+			name:     "case insensitive",
+			rules:    []Rule{domainRule([]string{"Example.com", "WWW.Sub.Example.org."}, nil)},
+			excludes: []Rule{domainRule([]string{"example.COM"}, []string{"example.org"})},
+			expected: nil,
+		},
+		{
+			name:     "ip cidr split",
+			rules:    []Rule{ipCIDRRule("10.0.0.0/8", "192.168.0.1")},
+			excludes: []Rule{ipCIDRRule("10.128.0.0/9", "192.168.0.1/32")},
+			expected: []Rule{ipCIDRRule("10.0.0.0/9")},
+		},
+		{
+			name: "or sub-rules",
+			rules: []Rule{logicalRule(C.LogicalTypeOr, false,
+				domainRule([]string{"a.com", "b.com"}, nil),
+				domainRule([]string{"c.com"}, nil),
+			)},
+			excludes: []Rule{domainRule([]string{"a.com", "c.com"}, nil)},
+			expected: []Rule{logicalRule(C.LogicalTypeOr, false,
+				domainRule([]string{"b.com"}, nil),
+			)},
+		},
+		{
+			name: "and sub-rule dropped",
+			rules: []Rule{logicalRule(C.LogicalTypeAnd, false,
+				domainRule(nil, []string{"a.com"}),
+				invertRule(domainRule([]string{"www.a.com"}, nil)),
+			)},
+			excludes: []Rule{domainRule(nil, []string{"a.com"})},
+			expected: nil,
+		},
+		{
+			name: "and keeps inverted sub-rule",
+			rules: []Rule{logicalRule(C.LogicalTypeAnd, false,
+				domainRule(nil, []string{"a.com", "b.com"}),
+				invertRule(domainRule([]string{"www.a.com"}, nil)),
+			)},
+			excludes: []Rule{domainRule([]string{"www.a.com"}, []string{"b.com"})},
+			expected: []Rule{logicalRule(C.LogicalTypeAnd, false,
+				domainRule(nil, []string{"a.com"}),
+				invertRule(domainRule([]string{"www.a.com"}, nil)),
+			)},
+		},
+		{
+			name:     "inverted rules unchanged",
+			rules:    []Rule{invertRule(domainRule([]string{"a.com"}, nil)), logicalRule(C.LogicalTypeOr, true, domainRule([]string{"a.com"}, nil))},
+			excludes: []Rule{domainRule([]string{"a.com"}, nil)},
+			expected: []Rule{invertRule(domainRule([]string{"a.com"}, nil)), logicalRule(C.LogicalTypeOr, true, domainRule([]string{"a.com"}, nil))},
+		},
+		{
+			name:     "excludes in logical rules",
+			rules:    []Rule{domainRule([]string{"a.com", "b.com"}, nil)},
+			excludes: []Rule{logicalRule(C.LogicalTypeOr, false, domainRule([]string{"a.com"}, nil))},
+			expected: []Rule{domainRule([]string{"b.com"}, nil)},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, testCase.expected, ExcludeRules(testCase.rules, testCase.excludes))
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package adapter
+
+import (
+	"net/netip"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common"
+
+	"go4.org/netipx"
+)
+
+// AggregateIPCIDR replaces IP CIDR items of rules with the minimal list of prefixes covering the same addresses,
+// and returns the number of removed items.
+func AggregateIPCIDR(rules []Rule) ([]Rule, int) {
+	var removed int
+	outputRules := make([]Rule, 0, len(rules))
+	for _, rule := range rules {
+		var ruleRemoved int
+		if rule.Type == C.RuleTypeDefault {
+			rule.DefaultOptions, ruleRemoved = aggregateDefaultRule(rule.DefaultOptions)
+		} else {
+			rule.LogicalOptions.Rules, ruleRemoved = AggregateIPCIDR(rule.LogicalOptions.Rules)
+		}
+		removed += ruleRemoved
+		outputRules = append(outputRules, rule)
+	}
+	return outputRules, removed
+}
+
+func aggregateDefaultRule(rule DefaultRule) (DefaultRule, int) {
+	var ipCIDRRemoved, sourceIPCIDRRemoved int
+	rule.IPCIDR, ipCIDRRemoved = aggregateIPCIDR(rule.IPCIDR)
+	rule.SourceIPCIDR, sourceIPCIDRRemoved = aggregateIPCIDR(rule.SourceIPCIDR)
+	return rule, ipCIDRRemoved + sourceIPCIDRRemoved
+}
+
+func aggregateIPCIDR(ipCIDRs []string) ([]string, int) {
+	if len(ipCIDRs) == 0 {
+		return ipCIDRs, 0
+	}
+	var (
+		builder    netipx.IPSetBuilder
+		unparsable []string
+	)
+	for _, ipCIDR := range ipCIDRs {
+		prefix, err := parseIPCIDR(ipCIDR)
+		if err != nil {
+			unparsable = append(unparsable, ipCIDR)
+			continue
+		}
+		builder.AddPrefix(prefix)
+	}
+	ipSet, err := builder.IPSet()
+	if err != nil {
+		return ipCIDRs, 0
+	}
+	aggregated := append(common.Map(ipSet.Prefixes(), netip.Prefix.String), common.Uniq(unparsable)...)
+	return aggregated, len(ipCIDRs) - len(aggregated)
+}
@@ -0,0 +1,63 @@
+package adapter
+
+import (
+	"testing"
+
+	C "github.com/sagernet/sing-box/constant"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregateIPCIDR(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name     string
+		rules    []Rule
+		expected []Rule
+		removed  int
+	}{
+		{
+			name:     "adjacent",
+			rules:    []Rule{ipCIDRRule("10.0.0.0/9", "10.128.0.0/9")},
+			expected: []Rule{ipCIDRRule("10.0.0.0/8")},
+			removed:  1,
+		},
+		{
+			name:     "overlapping and duplicate",
+			rules:    []Rule{ipCIDRRule("192.168.0.0/16", "192.168.1.0/24", "192.168.0.0/16", "192.168.2.1")},
+			expected: []Rule{ipCIDRRule("192.168.0.0/16")},
+			removed:  3,
+		},
+		{
+			name:     "unmasked and addresses",
+			rules:    []Rule{ipCIDRRule("10.0.0.1/8", "2001:db8::1", "2001:db8::/128")},
+			expected: []Rule{ipCIDRRule("10.0.0.0/8", "2001:db8::/127")},
+			removed:  1,
+		},
+		{
+			name:     "unparsable kept",
+			rules:    []Rule{ipCIDRRule("1.1.1.1", "invalid", "invalid")},
+			expected: []Rule{ipCIDRRule("1.1.1.1/32", "invalid")},
+			removed:  1,
+		},
+		{
+			name: "logical",
+			rules: []Rule{logicalRule(C.LogicalTypeOr, false,
+				ipCIDRRule("10.0.0.0/9", "10.128.0.0/9"),
+				domainRule([]string{"a.com"}, nil),
+			)},
+			expected: []Rule{logicalRule(C.LogicalTypeOr, false,
+				ipCIDRRule("10.0.0.0/8"),
+				domainRule([]string{"a.com"}, nil),
+			)},
+			removed: 1,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			rules, removed := AggregateIPCIDR(testCase.rules)
+			require.Equal(t, testCase.expected, rules)
+			require.Equal(t, testCase.removed, removed)
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"reflect"
 
 	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common"
 )
 
 func MergeRules(rules []Rule) []Rule {
@@ -31,6 +32,13 @@ func mergeDestinationAddressRules(rules []Rule) []Rule {
 		}
 	}
 	if destinationRule != nil {
+		destinationRule.Domain = common.Uniq(destinationRule.Domain)
+		destinationRule.DomainSuffix = common.Uniq(destinationRule.DomainSuffix)
+		destinationRule.DomainKeyword = common.Uniq(destinationRule.DomainKeyword)
+		destinationRule.DomainRegex = common.Uniq(destinationRule.DomainRegex)
+		destinationRule.IPCIDR = common.Uniq(destinationRule.IPCIDR)
+		destinationRule.GEOIP = common.Uniq(destinationRule.GEOIP)
+		destinationRule.IPASN = common.Uniq(destinationRule.IPASN)
 		outputRules = append([]Rule{{Type: C.RuleTypeDefault, DefaultOptions: *destinationRule}}, outputRules...)
 	}
 	return outputRules
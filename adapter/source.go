@@ -18,4 +18,6 @@ type FetchResponseBody struct {
 	NotModified bool
 	ETag        string
 	LastUpdated time.Time
+	// Skipped is set if the fetch is skipped without a request to the source.
+	Skipped bool
 }
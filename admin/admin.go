@@ -0,0 +1,191 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/sing/service"
+	"github.com/sagernet/srsc/adapter"
+	"github.com/sagernet/srsc/endpoint"
+	"github.com/sagernet/srsc/option"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	ctx       context.Context
+	logger    logger.ContextLogger
+	cache     adapter.Cache
+	tokens    []string
+	endpoints map[string]endpoint.CachedEndpoint
+	router    *chi.Mux
+}
+
+type cacheEntry struct {
+	Key          string    `json:"key"`
+	LastUpdated  time.Time `json:"last_updated"`
+	LastModified time.Time `json:"last_modified"`
+	ETag         string    `json:"etag,omitempty"`
+	ContentEtag  string    `json:"content_etag"`
+	Size         int       `json:"size"`
+}
+
+type cacheResponse struct {
+	Entries []cacheEntry `json:"entries"`
+}
+
+type purgeResponse struct {
+	Purged int `json:"purged"`
+}
+
+type refreshResponse struct {
+	Endpoint string `json:"endpoint"`
+	Purged   int    `json:"purged"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func NewHandler(ctx context.Context, logger logger.ContextLogger, options option.AdminOptions, endpoints map[string]endpoint.CachedEndpoint) (*Handler, error) {
+	if len(options.Tokens) == 0 {
+		return nil, E.New("missing tokens")
+	}
+	h := &Handler{
+		ctx:       ctx,
+		logger:    logger,
+		cache:     service.FromContext[adapter.Cache](ctx),
+		tokens:    options.Tokens,
+		endpoints: endpoints,
+		router:    chi.NewRouter(),
+	}
+	h.router.Get("/cache", h.handler(h.listCache))
+	h.router.Delete("/cache", h.handler(h.purgeCache))
+	h.router.Post("/refresh", h.handler(h.refresh))
+	return h, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authenticate(r) {
+		h.logger.Warn("unauthorized admin request from ", r.RemoteAddr, ": ", r.Method, " ", r.URL)
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSON(w, http.StatusUnauthorized, errorResponse{"unauthorized"})
+		return
+	}
+	h.router.ServeHTTP(w, r)
+}
+
+func (h *Handler) authenticate(r *http.Request) bool {
+	token, loaded := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !loaded || token == "" {
+		return false
+	}
+	var matched bool
+	for _, expected := range h.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1 {
+			matched = true
+		}
+	}
+	return matched
+}
+
+func (h *Handler) handler(serve func(r *http.Request) (int, any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		statusCode, response, err := serve(r)
+		if err != nil {
+			h.logger.Error("handle admin request ", r.RemoteAddr, " \"", r.Method, " ", r.URL, "\": ", err)
+			writeJSON(w, statusCode, errorResponse{err.Error()})
+			return
+		}
+		h.logger.Debug("accepted admin request ", r.RemoteAddr, " \"", r.Method, " ", r.URL, "\"")
+		writeJSON(w, statusCode, response)
+	}
+}
+
+func (h *Handler) listCache(r *http.Request) (int, any, error) {
+	tags, err := h.cache.ListBinary(r.URL.Query().Get("prefix"))
+	if err != nil {
+		return http.StatusInternalServerError, nil, E.Cause(err, "list cache")
+	}
+	response := cacheResponse{
+		Entries: make([]cacheEntry, 0, len(tags)),
+	}
+	for _, tag := range tags {
+		savedBinary, err := h.cache.LoadBinary(tag)
+		if err != nil {
+			h.logger.Warn("skip cache entry ", tag, ": ", err)
+			continue
+		}
+		if savedBinary == nil {
+			continue
+		}
+		response.Entries = append(response.Entries, cacheEntry{
+			Key:          tag,
+			LastUpdated:  savedBinary.LastUpdated,
+			LastModified: savedBinary.LastModified,
+			ETag:         savedBinary.LastEtag,
+			ContentEtag:  savedBinary.ContentEtag,
+			Size:         len(savedBinary.Content),
+		})
+	}
+	return http.StatusOK, response, nil
+}
+
+func (h *Handler) purgeCache(r *http.Request) (int, any, error) {
+	query := r.URL.Query()
+	if key := query.Get("key"); key != "" {
+		err := h.cache.DeleteBinary(key)
+		if err != nil {
+			return http.StatusInternalServerError, nil, E.Cause(err, "delete cache binary: ", key)
+		}
+		h.logger.Info("purged cache ", key)
+		return http.StatusOK, purgeResponse{1}, nil
+	}
+	if !query.Has("prefix") {
+		return http.StatusBadRequest, nil, E.New("missing key or prefix to purge")
+	}
+	prefix := query.Get("prefix")
+	purged, err := adapter.PurgeBinary(h.cache, prefix)
+	if err != nil {
+		return http.StatusInternalServerError, nil, E.Cause(err, "purge cache")
+	}
+	h.logger.Info("purged ", purged, " cache entries with prefix ", prefix)
+	return http.StatusOK, purgeResponse{purged}, nil
+}
+
+func (h *Handler) refresh(r *http.Request) (int, any, error) {
+	path := r.URL.Query().Get("endpoint")
+	if path == "" {
+		return http.StatusBadRequest, nil, E.New("missing endpoint to refresh")
+	}
+	cachedEndpoint, loaded := h.endpoints[path]
+	if !loaded {
+		return http.StatusNotFound, nil, E.New("file or merge endpoint not found: ", path)
+	}
+	purged, err := cachedEndpoint.Refresh()
+	if err != nil {
+		return http.StatusBadGateway, nil, E.Cause(err, "refresh endpoint ", path)
+	}
+	h.logger.Info("refreshed endpoint ", path, ", purged ", purged, " cache entries")
+	return http.StatusOK, refreshResponse{path, purged}, nil
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, response any) {
+	buffer := new(bytes.Buffer)
+	err := json.NewEncoder(buffer).Encode(response)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	w.Write(buffer.Bytes())
+}
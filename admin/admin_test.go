@@ -0,0 +1,87 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/sing/service"
+	"github.com/sagernet/srsc/adapter"
+	"github.com/sagernet/srsc/cache"
+	"github.com/sagernet/srsc/endpoint"
+	"github.com/sagernet/srsc/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testEndpoint struct {
+	endpoint.CachedEndpoint
+	purged int
+	err    error
+}
+
+func (e *testEndpoint) Refresh() (int, error) {
+	return e.purged, e.err
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+	ctx := service.ContextWithDefaultRegistry(context.Background())
+	memoryCache := cache.NewMemory(time.Hour)
+	for _, tag := range []string{"file.0.a", "file.0.b", "merge.0.c"} {
+		require.NoError(t, memoryCache.SaveBinary(tag, &adapter.SavedBinary{Content: []byte(tag)}))
+	}
+	service.MustRegister[adapter.Cache](ctx, memoryCache)
+	handler, err := NewHandler(ctx, logger.NOP(), option.AdminOptions{Tokens: []string{"token"}}, map[string]endpoint.CachedEndpoint{
+		"/test":   &testEndpoint{purged: 2},
+		"/failed": &testEndpoint{err: errors.New("source unavailable")},
+	})
+	require.NoError(t, err)
+	// requests are sent in order, purges affect later requests
+	for _, testCase := range []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		status int
+		body   string
+		keys   []string
+	}{
+		{name: "missing token", method: "GET", path: "/cache", status: http.StatusUnauthorized, body: `{"error":"unauthorized"}`},
+		{name: "invalid token", method: "GET", path: "/cache", token: "invalid", status: http.StatusUnauthorized, body: `{"error":"unauthorized"}`},
+		{name: "list", method: "GET", path: "/cache", token: "token", status: http.StatusOK, keys: []string{"file.0.a", "file.0.b", "merge.0.c"}},
+		{name: "list prefix", method: "GET", path: "/cache?prefix=file.", token: "token", status: http.StatusOK, keys: []string{"file.0.a", "file.0.b"}},
+		{name: "purge without key", method: "DELETE", path: "/cache", token: "token", status: http.StatusBadRequest, body: `{"error":"missing key or prefix to purge"}`},
+		{name: "purge key", method: "DELETE", path: "/cache?key=file.0.a", token: "token", status: http.StatusOK, body: `{"purged":1}`},
+		{name: "purge prefix", method: "DELETE", path: "/cache?prefix=merge.", token: "token", status: http.StatusOK, body: `{"purged":1}`},
+		{name: "list purged", method: "GET", path: "/cache", token: "token", status: http.StatusOK, keys: []string{"file.0.b"}},
+		{name: "refresh without endpoint", method: "POST", path: "/refresh", token: "token", status: http.StatusBadRequest, body: `{"error":"missing endpoint to refresh"}`},
+		{name: "refresh unknown endpoint", method: "POST", path: "/refresh?endpoint=/none", token: "token", status: http.StatusNotFound, body: `{"error":"file or merge endpoint not found: /none"}`},
+		{name: "refresh", method: "POST", path: "/refresh?endpoint=/test", token: "token", status: http.StatusOK, body: `{"endpoint":"/test","purged":2}`},
+		{name: "refresh failed", method: "POST", path: "/refresh?endpoint=/failed", token: "token", status: http.StatusBadGateway, body: `{"error":"refresh endpoint /failed: source unavailable"}`},
+	} {
+		request := httptest.NewRequest(testCase.method, testCase.path, nil)
+		if testCase.token != "" {
+			request.Header.Set("Authorization", "Bearer "+testCase.token)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		require.Equal(t, testCase.status, recorder.Code, testCase.name)
+		if testCase.keys != nil {
+			var response cacheResponse
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response), testCase.name)
+			var keys []string
+			for _, entry := range response.Entries {
+				keys = append(keys, entry.Key)
+			}
+			require.Equal(t, testCase.keys, keys, testCase.name)
+		} else {
+			require.JSONEq(t, testCase.body, recorder.Body.String(), testCase.name)
+		}
+	}
+}
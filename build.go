@@ -0,0 +1,213 @@
+package srsc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/json/badoption"
+	"github.com/sagernet/srsc/endpoint"
+)
+
+type route struct {
+	pattern     string
+	buildParams map[string]badoption.Listable[string]
+	formats     []string
+}
+
+type BuildManifest struct {
+	Files []BuildFile `json:"files"`
+}
+
+type BuildFile struct {
+	Path        string `json:"path"`
+	Size        int    `json:"size"`
+	SHA256      string `json:"sha256"`
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// Build requests every endpoint with all combinations of its build params,
+// writes responses to outputDirectory in paths mirroring the routes, and writes manifest.json.
+//
+// Endpoints with multiple targets are built once per target format, with the format appended to the path.
+func (s *Server) Build(outputDirectory string, userAgent string) (*BuildManifest, error) {
+	err := s.cache.Start()
+	if err != nil {
+		return nil, E.Cause(err, "start cache")
+	}
+	var manifest BuildManifest
+	for _, route := range s.routes {
+		requestPaths, err := route.requestPaths()
+		if err != nil {
+			return nil, E.Cause(err, "build endpoint ", route.pattern)
+		}
+		for _, requestPath := range requestPaths {
+			if len(route.formats) == 0 {
+				buildFile, err := s.buildPath(outputDirectory, requestPath, "", userAgent)
+				if err != nil {
+					return nil, E.Cause(err, "build ", requestPath)
+				}
+				manifest.Files = append(manifest.Files, *buildFile)
+				s.logger.Info("built ", requestPath)
+				continue
+			}
+			for _, format := range route.formats {
+				buildFile, err := s.buildPath(outputDirectory, requestPath, format, userAgent)
+				if err != nil {
+					return nil, E.Cause(err, "build ", requestPath, " in format ", format)
+				}
+				manifest.Files = append(manifest.Files, *buildFile)
+				s.logger.Info("built ", buildFile.Path)
+			}
+		}
+	}
+	buffer := new(bytes.Buffer)
+	encoder := json.NewEncoder(buffer)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(manifest)
+	if err != nil {
+		return nil, E.Cause(err, "encode manifest")
+	}
+	err = os.WriteFile(filepath.Join(outputDirectory, "manifest.json"), buffer.Bytes(), 0o644)
+	if err != nil {
+		return nil, E.Cause(err, "write manifest")
+	}
+	return &manifest, nil
+}
+
+// buildPath requests requestPath in format, or in the default format if empty, and writes the response to outputDirectory.
+func (s *Server) buildPath(outputDirectory string, requestPath string, format string, userAgent string) (*BuildFile, error) {
+	if strings.HasSuffix(requestPath, "/") {
+		return nil, E.New("unable to build directory path")
+	}
+	requestURL, outputPath := requestPath, requestPath
+	if format != "" {
+		requestURL += "?format=" + url.QueryEscape(format)
+		outputPath += "." + format
+	}
+	filePath := filepath.Join(outputDirectory, filepath.FromSlash(outputPath))
+	relativePath, err := filepath.Rel(outputDirectory, filePath)
+	if err != nil || relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+		return nil, E.New("path is outside of the output directory: ", outputPath)
+	}
+	request, err := http.NewRequestWithContext(endpoint.ContextWithInternalRequest(s.ctx), http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if userAgent != "" {
+		request.Header.Set("User-Agent", userAgent)
+	}
+	recorder := httptest.NewRecorder()
+	s.router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		return nil, E.New("unexpected status: ", recorder.Code)
+	}
+	content := recorder.Body.Bytes()
+	err = os.MkdirAll(filepath.Dir(filePath), 0o755)
+	if err != nil {
+		return nil, err
+	}
+	err = os.WriteFile(filePath, content, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	contentHash := sha256.Sum256(content)
+	return &BuildFile{
+		Path:        outputPath,
+		Size:        len(content),
+		SHA256:      hex.EncodeToString(contentHash[:]),
+		ContentType: recorder.Header().Get("Content-Type"),
+	}, nil
+}
+
+func (r route) requestPaths() ([]string, error) {
+	segments, paramNames, err := splitPattern(r.pattern)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(r.pattern, "*") {
+		return nil, E.New("wildcard routes are not supported")
+	}
+	combinations := []map[string]string{{}}
+	for _, paramName := range paramNames {
+		values := r.buildParams[paramName]
+		if len(values) == 0 {
+			return nil, E.New("missing build params for ", paramName)
+		}
+		for _, value := range values {
+			if value == "" || strings.Contains(value, "..") || strings.ContainsAny(value, "/\\") {
+				return nil, E.New("invalid build param for ", paramName, ": ", value)
+			}
+		}
+		var nextCombinations []map[string]string
+		for _, combination := range combinations {
+			for _, value := range values {
+				nextCombination := make(map[string]string, len(combination)+1)
+				for key, combinationValue := range combination {
+					nextCombination[key] = combinationValue
+				}
+				nextCombination[paramName] = value
+				nextCombinations = append(nextCombinations, nextCombination)
+			}
+		}
+		combinations = nextCombinations
+	}
+	requestPaths := make([]string, 0, len(combinations))
+	for _, combination := range combinations {
+		var pathBuilder strings.Builder
+		for index, segment := range segments {
+			pathBuilder.WriteString(segment)
+			if index < len(paramNames) {
+				pathBuilder.WriteString(combination[paramNames[index]])
+			}
+		}
+		requestPaths = append(requestPaths, pathBuilder.String())
+	}
+	sort.Strings(requestPaths)
+	return requestPaths, nil
+}
+
+// splitPattern splits a chi routing pattern into static segments and URL param names,
+// len(segments) == len(paramNames) + 1.
+func splitPattern(pattern string) ([]string, []string, error) {
+	var (
+		segments   []string
+		paramNames []string
+	)
+	for {
+		start := strings.IndexByte(pattern, '{')
+		if start == -1 {
+			segments = append(segments, pattern)
+			return segments, paramNames, nil
+		}
+		depth := 0
+		end := -1
+		for i := start; i < len(pattern); i++ {
+			if pattern[i] == '{' {
+				depth++
+			} else if pattern[i] == '}' {
+				depth--
+				if depth == 0 {
+					end = i
+					break
+				}
+			}
+		}
+		if end == -1 {
+			return nil, nil, E.New("unclosed URL param in pattern")
+		}
+		paramName, _, _ := strings.Cut(pattern[start+1:end], ":")
+		segments = append(segments, pattern[:start])
+		paramNames = append(paramNames, paramName)
+		pattern = pattern[end+1:]
+	}
+}
@@ -0,0 +1,88 @@
+package srsc
+
+import (
+	"testing"
+
+	"github.com/sagernet/sing/common/json/badoption"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteRequestPaths(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name        string
+		pattern     string
+		buildParams map[string]badoption.Listable[string]
+		expected    []string
+		expectError bool
+	}{
+		{
+			name:     "static",
+			pattern:  "/ads.srs",
+			expected: []string{"/ads.srs"},
+		},
+		{
+			name:        "single param",
+			pattern:     "/geosite/{code}.srs",
+			buildParams: map[string]badoption.Listable[string]{"code": {"google", "cn"}},
+			expected:    []string{"/geosite/cn.srs", "/geosite/google.srs"},
+		},
+		{
+			name:        "combinations",
+			pattern:     "/{type}/{code:[a-z]+}.srs",
+			buildParams: map[string]badoption.Listable[string]{"type": {"geoip", "geosite"}, "code": {"cn", "us"}},
+			expected:    []string{"/geoip/cn.srs", "/geoip/us.srs", "/geosite/cn.srs", "/geosite/us.srs"},
+		},
+		{
+			name:        "missing params",
+			pattern:     "/geosite/{code}.srs",
+			expectError: true,
+		},
+		{
+			name:        "parent directory",
+			pattern:     "/geosite/{code}.srs",
+			buildParams: map[string]badoption.Listable[string]{"code": {"cn", ".."}},
+			expectError: true,
+		},
+		{
+			name:        "path separator",
+			pattern:     "/geosite/{code}.srs",
+			buildParams: map[string]badoption.Listable[string]{"code": {"../../etc/passwd"}},
+			expectError: true,
+		},
+		{
+			name:        "windows path separator",
+			pattern:     "/geosite/{code}.srs",
+			buildParams: map[string]badoption.Listable[string]{"code": {"a\\b"}},
+			expectError: true,
+		},
+		{
+			name:        "empty value",
+			pattern:     "/geosite/{code}.srs",
+			buildParams: map[string]badoption.Listable[string]{"code": {""}},
+			expectError: true,
+		},
+		{
+			name:        "wildcard",
+			pattern:     "/rules/*",
+			expectError: true,
+		},
+		{
+			name:        "unclosed param",
+			pattern:     "/geosite/{code.srs",
+			expectError: true,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			requestPaths, err := route{pattern: testCase.pattern, buildParams: testCase.buildParams}.requestPaths()
+			if testCase.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testCase.expected, requestPaths)
+		})
+	}
+}
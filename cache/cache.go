@@ -15,6 +15,8 @@ func New(ctx context.Context, options option.CacheOptions) (adapter.Cache, error
 		return NewMemory(options.Expiration), nil
 	case C.CacheTypeRedis:
 		return NewRedis(ctx, options.Expiration, options.RedisOptions)
+	case C.CacheTypeFile:
+		return NewFile(ctx, options.Expiration, options.FileOptions), nil
 	default:
 		return nil, E.New("unknown cache type: ", options.Type)
 	}
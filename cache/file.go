@@ -0,0 +1,223 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/sagernet/bbolt"
+	bboltErrors "github.com/sagernet/bbolt/errors"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/service/filemanager"
+	"github.com/sagernet/srsc/adapter"
+	"github.com/sagernet/srsc/option"
+)
+
+var _ adapter.Cache = (*FileCache)(nil)
+
+var bucketBinary = []byte("binary")
+
+type FileCache struct {
+	ctx        context.Context
+	path       string
+	expiration time.Duration
+	db         *bbolt.DB
+}
+
+func NewFile(ctx context.Context, expiration time.Duration, options option.FileCacheOptions) *FileCache {
+	var path string
+	if options.Path != "" {
+		path = options.Path
+	} else {
+		path = "cache.db"
+	}
+	return &FileCache{
+		ctx:        ctx,
+		path:       filemanager.BasePath(ctx, path),
+		expiration: expiration,
+	}
+}
+
+func (c *FileCache) Start() error {
+	const fileMode = 0o666
+	options := bbolt.Options{Timeout: time.Second}
+	var (
+		db  *bbolt.DB
+		err error
+	)
+	for i := 0; i < 10; i++ {
+		db, err = bbolt.Open(c.path, fileMode, &options)
+		if err == nil {
+			break
+		}
+		if errors.Is(err, bboltErrors.ErrTimeout) {
+			continue
+		}
+		if E.IsMulti(err, bboltErrors.ErrInvalid, bboltErrors.ErrChecksum, bboltErrors.ErrVersionMismatch) {
+			rmErr := os.Remove(c.path)
+			if rmErr != nil {
+				return err
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err != nil {
+		return E.Cause(err, "open cache file")
+	}
+	err = filemanager.Chown(c.ctx, c.path)
+	if err != nil {
+		db.Close()
+		return E.Cause(err, "platform chown")
+	}
+	now := time.Now()
+	err = db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(bucketBinary)
+		if err != nil {
+			return err
+		}
+		cursor := bucket.Cursor()
+		for key, value := cursor.First(); key != nil; key, value = cursor.Next() {
+			if isExpired(value, now) {
+				err = cursor.Delete()
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return E.Cause(err, "initialize cache file")
+	}
+	c.db = db
+	return nil
+}
+
+func (c *FileCache) Close() error {
+	if c.db == nil {
+		return nil
+	}
+	return c.db.Close()
+}
+
+func (c *FileCache) LoadBinary(tag string) (*adapter.SavedBinary, error) {
+	if c.db == nil {
+		return nil, nil
+	}
+	var (
+		binaryBytes []byte
+		expired     bool
+	)
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketBinary)
+		if bucket == nil {
+			return nil
+		}
+		value := bucket.Get([]byte(tag))
+		if value == nil {
+			return nil
+		}
+		if isExpired(value, time.Now()) {
+			expired = true
+			return nil
+		}
+		binaryBytes = append([]byte(nil), value[8:]...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if expired {
+		err = c.db.Update(func(tx *bbolt.Tx) error {
+			bucket := tx.Bucket(bucketBinary)
+			if bucket == nil {
+				return nil
+			}
+			return bucket.Delete([]byte(tag))
+		})
+		return nil, err
+	}
+	if binaryBytes == nil {
+		return nil, nil
+	}
+	binary := &adapter.SavedBinary{}
+	err = binary.UnmarshalBinary(binaryBytes)
+	if err != nil {
+		return nil, err
+	}
+	return binary, nil
+}
+
+func (c *FileCache) SaveBinary(tag string, savedBinary *adapter.SavedBinary) error {
+	if c.db == nil {
+		return E.New("cache file not started")
+	}
+	binaryBytes, err := savedBinary.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	var expireAt int64
+	if c.expiration > 0 {
+		expireAt = time.Now().Add(c.expiration).Unix()
+	}
+	value := make([]byte, 8+len(binaryBytes))
+	binary.BigEndian.PutUint64(value, uint64(expireAt))
+	copy(value[8:], binaryBytes)
+	return c.db.Batch(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(bucketBinary)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(tag), value)
+	})
+}
+
+func (c *FileCache) DeleteBinary(tag string) error {
+	if c.db == nil {
+		return E.New("cache file not started")
+	}
+	return c.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketBinary)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.Delete([]byte(tag))
+	})
+}
+
+func (c *FileCache) ListBinary(prefix string) ([]string, error) {
+	if c.db == nil {
+		return nil, nil
+	}
+	var tags []string
+	now := time.Now()
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketBinary)
+		if bucket == nil {
+			return nil
+		}
+		cursor := bucket.Cursor()
+		for key, value := cursor.Seek([]byte(prefix)); key != nil && bytes.HasPrefix(key, []byte(prefix)); key, value = cursor.Next() {
+			if !isExpired(value, now) {
+				tags = append(tags, string(key))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func isExpired(value []byte, now time.Time) bool {
+	if len(value) < 8 {
+		return true
+	}
+	expireAt := int64(binary.BigEndian.Uint64(value))
+	return expireAt > 0 && now.Unix() >= expireAt
+}
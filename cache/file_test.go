@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sagernet/bbolt"
+	"github.com/sagernet/srsc/adapter"
+	"github.com/sagernet/srsc/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFileCache(t *testing.T, path string, expiration time.Duration) *FileCache {
+	fileCache := NewFile(context.Background(), expiration, option.FileCacheOptions{Path: path})
+	require.NoError(t, fileCache.Start())
+	t.Cleanup(func() {
+		fileCache.Close()
+	})
+	return fileCache
+}
+
+// expireBinary rewrites the expiration time of tag to the past.
+func expireBinary(t *testing.T, fileCache *FileCache, tag string) {
+	require.NoError(t, fileCache.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketBinary)
+		value := append([]byte(nil), bucket.Get([]byte(tag))...)
+		binary.BigEndian.PutUint64(value, uint64(time.Now().Add(-time.Second).Unix()))
+		return bucket.Put([]byte(tag), value)
+	}))
+}
+
+func TestFileCacheReopen(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "cache.db")
+	fileCache := newTestFileCache(t, path, 0)
+	savedBinary := &adapter.SavedBinary{
+		Content:     []byte("content"),
+		LastUpdated: time.Unix(1700000000, 0),
+		LastEtag:    "etag",
+		ContentEtag: adapter.ContentEtag([]byte("content")),
+	}
+	require.NoError(t, fileCache.SaveBinary("file.0.a", savedBinary))
+	require.NoError(t, fileCache.Close())
+
+	fileCache = newTestFileCache(t, path, 0)
+	loadedBinary, err := fileCache.LoadBinary("file.0.a")
+	require.NoError(t, err)
+	require.NotNil(t, loadedBinary)
+	require.Equal(t, savedBinary.Content, loadedBinary.Content)
+	require.Equal(t, savedBinary.LastEtag, loadedBinary.LastEtag)
+	require.True(t, savedBinary.LastUpdated.Equal(loadedBinary.LastUpdated))
+}
+
+func TestFileCacheExpiration(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "cache.db")
+	fileCache := newTestFileCache(t, path, time.Hour)
+	for _, tag := range []string{"file.0.a", "file.0.b", "file.0.c"} {
+		require.NoError(t, fileCache.SaveBinary(tag, &adapter.SavedBinary{Content: []byte(tag)}))
+	}
+	expireBinary(t, fileCache, "file.0.a")
+	expireBinary(t, fileCache, "file.0.b")
+
+	tags, err := fileCache.ListBinary("file.0.")
+	require.NoError(t, err)
+	require.Equal(t, []string{"file.0.c"}, tags)
+
+	loadedBinary, err := fileCache.LoadBinary("file.0.a")
+	require.NoError(t, err)
+	require.Nil(t, loadedBinary)
+	require.NoError(t, fileCache.db.View(func(tx *bbolt.Tx) error {
+		require.Nil(t, tx.Bucket(bucketBinary).Get([]byte("file.0.a")), "expired binary is deleted on load")
+		return nil
+	}))
+	require.NoError(t, fileCache.Close())
+
+	fileCache = newTestFileCache(t, path, time.Hour)
+	require.NoError(t, fileCache.db.View(func(tx *bbolt.Tx) error {
+		require.Nil(t, tx.Bucket(bucketBinary).Get([]byte("file.0.b")), "expired binary is deleted on start")
+		return nil
+	}))
+	loadedBinary, err = fileCache.LoadBinary("file.0.c")
+	require.NoError(t, err)
+	require.NotNil(t, loadedBinary)
+}
+
+func TestFileCacheCorrupted(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "cache.db")
+	require.NoError(t, os.WriteFile(path, make([]byte, 16384), 0o644))
+	fileCache := newTestFileCache(t, path, 0)
+	loadedBinary, err := fileCache.LoadBinary("file.0.a")
+	require.NoError(t, err)
+	require.Nil(t, loadedBinary)
+	require.NoError(t, fileCache.SaveBinary("file.0.a", &adapter.SavedBinary{Content: []byte("content")}))
+	loadedBinary, err = fileCache.LoadBinary("file.0.a")
+	require.NoError(t, err)
+	require.Equal(t, []byte("content"), loadedBinary.Content)
+}
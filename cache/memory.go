@@ -1,6 +1,8 @@
 package cache
 
 import (
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/sagernet/sing/common"
@@ -43,3 +45,20 @@ func (c *MemoryCache) SaveBinary(tag string, binary *adapter.SavedBinary) error
 	c.Add(tag, binary)
 	return nil
 }
+
+func (c *MemoryCache) DeleteBinary(tag string) error {
+	c.Remove(tag)
+	return nil
+}
+
+func (c *MemoryCache) ListBinary(prefix string) ([]string, error) {
+	c.PurgeExpired()
+	var tags []string
+	for _, tag := range c.Keys() {
+		if strings.HasPrefix(tag, prefix) {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"net"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/sagernet/sing-box/common/tls"
@@ -17,6 +20,9 @@ import (
 
 var _ adapter.Cache = (*RedisCache)(nil)
 
+// redisKeyPrefix separates srsc keys from other keys in the same database.
+const redisKeyPrefix = "srsc:"
+
 type RedisCache struct {
 	ctx        context.Context
 	options    *redis.UniversalOptions
@@ -78,6 +84,23 @@ func (r *RedisCache) Close() error {
 }
 
 func (r *RedisCache) LoadBinary(tag string) (*adapter.SavedBinary, error) {
+	binaryBytes, err := r.client.Get(r.ctx, redisKeyPrefix+tag).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return r.loadLegacyBinary(tag)
+	} else if err != nil {
+		return nil, err
+	}
+	binary := &adapter.SavedBinary{}
+	err = binary.UnmarshalBinary(binaryBytes)
+	if err != nil {
+		return nil, err
+	}
+	return binary, nil
+}
+
+// loadLegacyBinary loads the key saved without prefix by previous versions,
+// which is deleted once the tag is saved or deleted.
+func (r *RedisCache) loadLegacyBinary(tag string) (*adapter.SavedBinary, error) {
 	binaryBytes, err := r.client.Get(r.ctx, tag).Bytes()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
@@ -89,7 +112,8 @@ func (r *RedisCache) LoadBinary(tag string) (*adapter.SavedBinary, error) {
 	binary := &adapter.SavedBinary{}
 	err = binary.UnmarshalBinary(binaryBytes)
 	if err != nil {
-		return nil, err
+		// not saved by srsc
+		return nil, nil
 	}
 	return binary, nil
 }
@@ -99,9 +123,65 @@ func (r *RedisCache) SaveBinary(tag string, binary *adapter.SavedBinary) error {
 	if err != nil {
 		return err
 	}
-	err = r.client.Set(r.ctx, tag, binaryBytes, r.expiration).Err()
+	err = r.client.Set(r.ctx, redisKeyPrefix+tag, binaryBytes, r.expiration).Err()
 	if err != nil {
 		return err
 	}
-	return nil
+	return r.client.Del(r.ctx, tag).Err()
+}
+
+func (r *RedisCache) DeleteBinary(tag string) error {
+	return r.client.Del(r.ctx, redisKeyPrefix+tag, tag).Err()
+}
+
+var redisPatternReplacer = strings.NewReplacer("\\", "\\\\", "*", "\\*", "?", "\\?", "[", "\\[", "]", "\\]")
+
+func (r *RedisCache) ListBinary(prefix string) ([]string, error) {
+	pattern := redisPatternReplacer.Replace(redisKeyPrefix+prefix) + "*"
+	var tags []string
+	if clusterClient, isCluster := r.client.(*redis.ClusterClient); isCluster {
+		var access sync.Mutex
+		err := clusterClient.ForEachMaster(r.ctx, func(ctx context.Context, client *redis.Client) error {
+			keys, err := scanKeys(ctx, client, pattern)
+			if err != nil {
+				return err
+			}
+			access.Lock()
+			tags = append(tags, keys...)
+			access.Unlock()
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		keys, err := scanKeys(r.ctx, r.client, pattern)
+		if err != nil {
+			return nil, err
+		}
+		tags = keys
+	}
+	for index, tag := range tags {
+		tags[index] = strings.TrimPrefix(tag, redisKeyPrefix)
+	}
+	sort.Strings(tags)
+	return common.Uniq(tags), nil
+}
+
+func scanKeys(ctx context.Context, client redis.Cmdable, pattern string) ([]string, error) {
+	var (
+		keys   []string
+		cursor uint64
+	)
+	for {
+		batch, nextCursor, err := client.Scan(ctx, cursor, pattern, 1024).Result()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, batch...)
+		if nextCursor == 0 {
+			return keys, nil
+		}
+		cursor = nextCursor
+	}
 }
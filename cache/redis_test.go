@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sagernet/srsc/adapter"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+// testRedisClient implements the commands used by RedisCache in memory.
+type testRedisClient struct {
+	redis.UniversalClient
+	access sync.Mutex
+	values map[string]string
+}
+
+func (c *testRedisClient) Get(ctx context.Context, key string) *redis.StringCmd {
+	c.access.Lock()
+	defer c.access.Unlock()
+	value, loaded := c.values[key]
+	if !loaded {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(value, nil)
+}
+
+func (c *testRedisClient) Set(ctx context.Context, key string, value any, _ time.Duration) *redis.StatusCmd {
+	c.access.Lock()
+	defer c.access.Unlock()
+	c.values[key] = string(value.([]byte))
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (c *testRedisClient) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	c.access.Lock()
+	defer c.access.Unlock()
+	var deleted int64
+	for _, key := range keys {
+		if _, loaded := c.values[key]; loaded {
+			delete(c.values, key)
+			deleted++
+		}
+	}
+	return redis.NewIntResult(deleted, nil)
+}
+
+// Scan only supports escaped prefix patterns used by ListBinary.
+func (c *testRedisClient) Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd {
+	c.access.Lock()
+	defer c.access.Unlock()
+	prefix := strings.NewReplacer("\\\\", "\\", "\\*", "*", "\\?", "?", "\\[", "[", "\\]", "]").Replace(strings.TrimSuffix(match, "*"))
+	var keys []string
+	for key := range c.values {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return redis.NewScanCmdResult(keys, 0, nil)
+}
+
+func newTestRedisCache(values map[string]string) (*RedisCache, *testRedisClient) {
+	client := &testRedisClient{values: values}
+	return &RedisCache{ctx: context.Background(), client: client}, client
+}
+
+func savedBinaryString(t *testing.T, content string) string {
+	binaryBytes, err := (&adapter.SavedBinary{Content: []byte(content)}).MarshalBinary()
+	require.NoError(t, err)
+	return string(binaryBytes)
+}
+
+func TestRedisListDelete(t *testing.T) {
+	t.Parallel()
+	redisCache, client := newTestRedisCache(map[string]string{
+		"srsc:file.0.a":     savedBinaryString(t, "a"),
+		"srsc:file.0.b":     savedBinaryString(t, "b"),
+		"srsc:res.geoip.cn": savedBinaryString(t, "cn"),
+		"file.0.c":          "other",
+		"other:file.0.d":    "other",
+	})
+	tags, err := redisCache.ListBinary("file.")
+	require.NoError(t, err)
+	require.Equal(t, []string{"file.0.a", "file.0.b"}, tags)
+	tags, err = redisCache.ListBinary("")
+	require.NoError(t, err)
+	require.Equal(t, []string{"file.0.a", "file.0.b", "res.geoip.cn"}, tags)
+	require.NoError(t, redisCache.DeleteBinary("file.0.a"))
+	require.NoError(t, redisCache.DeleteBinary("file.0.d"))
+	require.Equal(t, map[string]string{
+		"srsc:file.0.b":     savedBinaryString(t, "b"),
+		"srsc:res.geoip.cn": savedBinaryString(t, "cn"),
+		"file.0.c":          "other",
+		"other:file.0.d":    "other",
+	}, client.values)
+}
+
+func TestRedisLegacyKey(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name     string
+		values   map[string]string
+		expected string
+	}{
+		{"prefixed", map[string]string{"srsc:a": savedBinaryString(t, "new"), "a": savedBinaryString(t, "old")}, "new"},
+		{"legacy", map[string]string{"a": savedBinaryString(t, "old")}, "old"},
+		{"legacy not saved by srsc", map[string]string{"a": "other"}, ""},
+		{"missing", map[string]string{}, ""},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			redisCache, _ := newTestRedisCache(testCase.values)
+			binary, err := redisCache.LoadBinary("a")
+			require.NoError(t, err)
+			if testCase.expected == "" {
+				require.Nil(t, binary)
+			} else {
+				require.Equal(t, testCase.expected, string(binary.Content))
+			}
+		})
+	}
+	t.Run("save", func(t *testing.T) {
+		t.Parallel()
+		redisCache, client := newTestRedisCache(map[string]string{"a": savedBinaryString(t, "old")})
+		require.NoError(t, redisCache.SaveBinary("a", &adapter.SavedBinary{Content: []byte("new")}))
+		require.Equal(t, map[string]string{"srsc:a": savedBinaryString(t, "new")}, client.values)
+	})
+	t.Run("delete", func(t *testing.T) {
+		t.Parallel()
+		redisCache, client := newTestRedisCache(map[string]string{"a": savedBinaryString(t, "old")})
+		require.NoError(t, redisCache.DeleteBinary("a"))
+		require.Empty(t, client.values)
+		binary, err := redisCache.LoadBinary("a")
+		require.NoError(t, err)
+		require.Nil(t, binary)
+	})
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/srsc"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	commandBuildFlagOutput    string
+	commandBuildFlagUserAgent string
+)
+
+var commandBuild = &cobra.Command{
+	Use:   "build",
+	Short: "Build static rule-sets from configuration",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := build()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+	Args: cobra.NoArgs,
+}
+
+func init() {
+	commandBuild.Flags().StringVarP(&commandBuildFlagOutput, "output", "o", "build", "set output directory")
+	commandBuild.Flags().StringVar(&commandBuildFlagUserAgent, "user-agent", "", "set User-Agent of the emulated client")
+	mainCommand.AddCommand(commandBuild)
+}
+
+func build() error {
+	options, err := readConfigAndMerge()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(globalCtx)
+	defer cancel()
+	instance, err := srsc.NewServer(srsc.Options{
+		Context: ctx,
+		Options: options,
+	})
+	if err != nil {
+		return E.Cause(err, "create service")
+	}
+	_, err = instance.Build(commandBuildFlagOutput, commandBuildFlagUserAgent)
+	return E.Errors(err, instance.Close())
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/netip"
+	"os"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/json/badoption"
+	"github.com/sagernet/sing/service"
+	"github.com/sagernet/srsc/adapter"
+	"github.com/sagernet/srsc/cache"
+	C "github.com/sagernet/srsc/constant"
+	"github.com/sagernet/srsc/convertor"
+	"github.com/sagernet/srsc/option"
+	"github.com/sagernet/srsc/resource"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	commandConvertFlagInput       string
+	commandConvertFlagOutput      string
+	commandConvertFlagUserAgent   string
+	commandConvertFlagSinkAddress string
+	commandConvertFlagReport      bool
+	commandConvertOptions         option.ConvertOptions
+)
+
+var commandConvert = &cobra.Command{
+	Use:   "convert",
+	Short: "Convert rule-set",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := convert()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+	Args: cobra.NoArgs,
+}
+
+func init() {
+	flags := commandConvert.Flags()
+	flags.StringVarP(&commandConvertFlagInput, "input", "i", "stdin", "set input file path")
+	flags.StringVarP(&commandConvertFlagOutput, "output", "o", "stdout", "set output file path")
+	flags.StringVar(&commandConvertFlagUserAgent, "user-agent", "", "set User-Agent of the emulated client")
+	addSourceConvertFlags(commandConvert, &commandConvertOptions.SourceConvertOptions)
+	flags.StringVar(&commandConvertOptions.TargetType, "target-type", "", "set target convertor type")
+	flags.StringVar(&commandConvertOptions.TargetConvertOptions.ClashOptions.TargetFormat, "target-format", "", "set target format")
+	flags.StringVar(&commandConvertOptions.TargetConvertOptions.ClashOptions.TargetBehavior, "target-behavior", "", "set target behavior")
+	flags.StringVar(&commandConvertFlagSinkAddress, "sink-address", "", "set sink address of hosts file entries")
+	flags.BoolVar(&commandConvertOptions.AggregateIPCIDR, "aggregate-ip-cidr", false, "aggregate IP CIDR items")
+	flags.BoolVar(&commandConvertOptions.OptimizeDomain, "optimize-domain", false, "optimize domain items")
+	flags.BoolVar(&commandConvertOptions.FilterSystemItems, "filter-system-items", false, "remove rules unsupported on the system of the emulated client")
+	flags.BoolVar(&commandConvertOptions.Strict, "strict", false, "fail instead of dropping unsupported lines and rules")
+	flags.BoolVar(&commandConvertFlagReport, "report", false, "print dropped lines and rules to stderr")
+	commandConvert.MarkFlagRequired("target-type")
+	mainCommand.AddCommand(commandConvert)
+}
+
+func addSourceConvertFlags(command *cobra.Command, options *option.SourceConvertOptions) {
+	flags := command.Flags()
+	flags.StringVar(&options.SourceType, "source-type", "", "set source convertor type")
+	flags.BoolVar(&options.AdGuardOptions.AcceptExtendedRules, "accept-extended-rules", false, "accept extended AdGuard rules")
+	flags.StringVar(&options.ClashOptions.SourceFormat, "source-format", "", "set source format")
+	flags.StringVar(&options.ClashOptions.SourceBehavior, "source-behavior", "", "set source behavior")
+	command.MarkFlagRequired("source-type")
+}
+
+// createConvertContext creates a context with a memory cache and the resource manager from configuration,
+// resources are only configured when a configuration is specified.
+func createConvertContext() (context.Context, func(), error) {
+	var resourceOptions option.ResourceOptions
+	if mainCommand.PersistentFlags().Changed("config") || mainCommand.PersistentFlags().Changed("config-directory") {
+		options, err := readConfigAndMerge()
+		if err != nil {
+			return nil, nil, err
+		}
+		resourceOptions = common.PtrValueOrDefault(options.Resources)
+	}
+	ctx, cancel := context.WithCancel(service.ContextWithDefaultRegistry(globalCtx))
+	serviceCache, err := cache.New(ctx, option.CacheOptions{Type: C.CacheTypeMemory})
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	service.MustRegister[adapter.Cache](ctx, serviceCache)
+	resourceManager, err := resource.NewManager(ctx, log.StdLogger(), resourceOptions)
+	if err != nil {
+		cancel()
+		return nil, nil, E.Cause(err, "create resource manager")
+	}
+	service.MustRegister[adapter.ResourceManager](ctx, resourceManager)
+	return ctx, func() {
+		resourceManager.Close()
+		cancel()
+	}, nil
+}
+
+func readInput(path string) ([]byte, error) {
+	var (
+		content []byte
+		err     error
+	)
+	if path == "stdin" {
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, E.Cause(err, "read input")
+	}
+	return content, nil
+}
+
+func convert() error {
+	commandConvertOptions.SourceConvertOptions.SurgeOptions.SourceBehavior = commandConvertOptions.SourceConvertOptions.ClashOptions.SourceBehavior
+	commandConvertOptions.TargetConvertOptions.SurgeOptions.TargetBehavior = commandConvertOptions.TargetConvertOptions.ClashOptions.TargetBehavior
+	if commandConvertFlagSinkAddress != "" {
+		sinkAddress, err := netip.ParseAddr(commandConvertFlagSinkAddress)
+		if err != nil {
+			return E.Cause(err, "parse sink address")
+		}
+		commandConvertOptions.TargetConvertOptions.HostsOptions.SinkAddress = (*badoption.Addr)(&sinkAddress)
+	}
+	sourceConvertor, loaded := convertor.Convertors[commandConvertOptions.SourceType]
+	if !loaded {
+		return E.New("unknown source type: ", commandConvertOptions.SourceType)
+	}
+	targetConvertor, loaded := convertor.Convertors[commandConvertOptions.TargetType]
+	if !loaded {
+		return E.New("unknown target type: ", commandConvertOptions.TargetType)
+	}
+	ctx, closeContext, err := createConvertContext()
+	if err != nil {
+		return err
+	}
+	defer closeContext()
+	content, err := readInput(commandConvertFlagInput)
+	if err != nil {
+		return err
+	}
+	convertOptions := adapter.ConvertOptions{
+		Options:  commandConvertOptions,
+		Metadata: C.DetectMetadata(commandConvertFlagUserAgent),
+	}
+	if commandConvertFlagReport {
+		convertOptions.Diagnostics = adapter.NewDiagnostics()
+	}
+	binary, err := convertContent(ctx, sourceConvertor, targetConvertor, content, convertOptions)
+	if err != nil {
+		return err
+	}
+	if commandConvertFlagOutput == "stdout" {
+		_, err = os.Stdout.Write(binary)
+	} else {
+		err = os.WriteFile(commandConvertFlagOutput, binary, 0o644)
+	}
+	if err != nil {
+		return E.Cause(err, "write output")
+	}
+	if commandConvertFlagReport {
+		printReport(convertOptions.Diagnostics.Entries())
+	}
+	return nil
+}
+
+func convertContent(ctx context.Context, sourceConvertor adapter.Convertor, targetConvertor adapter.Convertor, content []byte, options adapter.ConvertOptions) ([]byte, error) {
+	rules, err := sourceConvertor.From(ctx, content, options)
+	if err != nil {
+		return nil, E.Cause(err, "decode source")
+	}
+	rules, err = convertor.OptimizeRules(ctx, log.StdLogger(), rules, options)
+	if err != nil {
+		return nil, err
+	}
+	binary, err := targetConvertor.To(ctx, rules, options)
+	if err != nil {
+		return nil, E.Cause(err, "encode target")
+	}
+	return binary, nil
+}
+
+func printReport(entries []adapter.Diagnostic) {
+	for _, entry := range entries {
+		os.Stderr.WriteString(F.ToString("dropped ", entry.Type, ": ", entry.Content, " (", entry.Reason, ")\n"))
+	}
+	droppedLines, droppedRules := adapter.DiagnosticCount(entries)
+	os.Stderr.WriteString(F.ToString("dropped ", droppedLines, " lines and ", droppedRules, " rules\n"))
+}
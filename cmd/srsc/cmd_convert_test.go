@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/service"
+	"github.com/sagernet/srsc/adapter"
+	"github.com/sagernet/srsc/cache"
+	C "github.com/sagernet/srsc/constant"
+	"github.com/sagernet/srsc/convertor"
+	"github.com/sagernet/srsc/option"
+	"github.com/sagernet/srsc/resource"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertContent(t *testing.T) {
+	t.Parallel()
+	ctx := service.ContextWithDefaultRegistry(context.Background())
+	service.MustRegister[adapter.Cache](ctx, cache.NewMemory(time.Minute))
+	service.MustRegister[adapter.ResourceManager](ctx, common.Must1(resource.NewManager(ctx, log.StdLogger(), option.ResourceOptions{})))
+	classicalSource := option.SourceConvertOptions{SourceType: C.ConvertorTypeClashRuleProvider}
+	classicalSource.ClashOptions.SourceFormat = "text"
+	classicalSource.ClashOptions.SourceBehavior = "classical"
+	for _, testCase := range []struct {
+		name     string
+		content  string
+		source   option.SourceConvertOptions
+		target   option.TargetConvertOptions
+		expected string
+		dropped  int
+		errorMsg string
+	}{
+		{
+			name:     "clash to hosts",
+			content:  "DOMAIN,a.com\nPROCESS-NAME,curl\n",
+			source:   classicalSource,
+			target:   option.TargetConvertOptions{TargetType: C.ConvertorTypeHostsFile},
+			expected: "0.0.0.0 a.com\n",
+			dropped:  1,
+		},
+		{
+			name:     "hosts to clash",
+			content:  "0.0.0.0 a.com\n0.0.0.0 b.a.com\n",
+			source:   option.SourceConvertOptions{SourceType: C.ConvertorTypeHostsFile},
+			target:   option.TargetConvertOptions{TargetType: C.ConvertorTypeClashRuleProvider, ClashOptions: option.ClashRuleProviderTargetOptions{TargetFormat: "text", TargetBehavior: "domain"}},
+			expected: "a.com\nb.a.com\n",
+		},
+		{
+			name:     "optimize domain",
+			content:  "DOMAIN,a.b.com\nDOMAIN-SUFFIX,b.com\n",
+			source:   classicalSource,
+			target:   option.TargetConvertOptions{TargetType: C.ConvertorTypeSurgeRuleSet, OptimizeDomain: true},
+			expected: "DOMAIN-SUFFIX,b.com",
+		},
+		{
+			name:     "strict",
+			content:  "DOMAIN,a.com\nPROCESS-NAME,curl\n",
+			source:   classicalSource,
+			target:   option.TargetConvertOptions{TargetType: C.ConvertorTypeHostsFile, Strict: true},
+			errorMsg: "encode target: strict mode: dropped rule {\"process_name\": \"curl\"}: unsupported by hosts file",
+		},
+		{
+			name:     "invalid source",
+			content:  "# empty\n",
+			source:   option.SourceConvertOptions{SourceType: C.ConvertorTypeHostsFile},
+			target:   option.TargetConvertOptions{TargetType: C.ConvertorTypeRuleSetSource},
+			errorMsg: "decode source: no domain found in hosts file",
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			diagnostics := adapter.NewDiagnostics()
+			binary, err := convertContent(ctx, convertor.Convertors[testCase.source.SourceType], convertor.Convertors[testCase.target.TargetType], []byte(testCase.content), adapter.ConvertOptions{
+				Options: option.ConvertOptions{
+					SourceConvertOptions: testCase.source,
+					TargetConvertOptions: testCase.target,
+				},
+				Diagnostics: diagnostics,
+			})
+			if testCase.errorMsg != "" {
+				require.EqualError(t, err, testCase.errorMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testCase.expected, string(binary))
+			require.Len(t, diagnostics.Entries(), testCase.dropped)
+		})
+	}
+}
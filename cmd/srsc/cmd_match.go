@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/netip"
+	"os"
+	"strconv"
+
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/srsc/adapter"
+	C "github.com/sagernet/srsc/constant"
+	"github.com/sagernet/srsc/match"
+	"github.com/sagernet/srsc/option"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	commandMatchFlagInput     string
+	commandMatchFlagUserAgent string
+	commandMatchFlagDomain    []string
+	commandMatchFlagIP        []string
+	commandMatchFlagPort      uint16
+	commandMatchFlagNetwork   string
+	commandMatchOptions       option.SourceConvertOptions
+)
+
+var commandMatch = &cobra.Command{
+	Use:   "match",
+	Short: "Match domains and IPs against rule-set",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := matchRuleSet()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+	Args: cobra.NoArgs,
+}
+
+func init() {
+	flags := commandMatch.Flags()
+	flags.StringVarP(&commandMatchFlagInput, "input", "i", "stdin", "set input file path")
+	flags.StringVar(&commandMatchFlagUserAgent, "user-agent", "", "set User-Agent of the emulated client")
+	addSourceConvertFlags(commandMatch, &commandMatchOptions)
+	flags.StringArrayVar(&commandMatchFlagDomain, "domain", nil, "domain to match")
+	flags.StringArrayVar(&commandMatchFlagIP, "ip", nil, "IP address to match")
+	flags.Uint16Var(&commandMatchFlagPort, "port", 0, "destination port of queries")
+	flags.StringVar(&commandMatchFlagNetwork, "network", "", "network of queries (tcp or udp)")
+	mainCommand.AddCommand(commandMatch)
+}
+
+func matchRuleSet() error {
+	if len(commandMatchFlagDomain) == 0 && len(commandMatchFlagIP) == 0 {
+		return E.New("missing domain or IP to match")
+	}
+	commandMatchOptions.SurgeOptions.SourceBehavior = commandMatchOptions.ClashOptions.SourceBehavior
+	var queries []match.Query
+	for _, domain := range commandMatchFlagDomain {
+		queries = append(queries, match.Query{
+			Domain:  domain,
+			Port:    commandMatchFlagPort,
+			Network: commandMatchFlagNetwork,
+		})
+	}
+	for _, ipString := range commandMatchFlagIP {
+		address, err := netip.ParseAddr(ipString)
+		if err != nil {
+			return E.Cause(err, "parse IP address")
+		}
+		queries = append(queries, match.Query{
+			IP:      address,
+			Port:    commandMatchFlagPort,
+			Network: commandMatchFlagNetwork,
+		})
+	}
+	ctx, closeContext, err := createConvertContext()
+	if err != nil {
+		return err
+	}
+	defer closeContext()
+	content, err := readInput(commandMatchFlagInput)
+	if err != nil {
+		return err
+	}
+	matcher, err := match.NewFromSource(ctx, content, adapter.ConvertOptions{
+		Options:  option.ConvertOptions{SourceConvertOptions: commandMatchOptions},
+		Metadata: C.DetectMetadata(commandMatchFlagUserAgent),
+	})
+	if err != nil {
+		return err
+	}
+	for _, query := range queries {
+		os.Stdout.WriteString(F.ToString(query, ": ", formatMatchResult(matcher.Match(query)), "\n"))
+	}
+	return nil
+}
+
+func formatMatchResult(result match.Result) string {
+	if !result.Matched {
+		return "not matched"
+	}
+	message := "matched rule[" + strconv.Itoa(result.RuleIndex) + "]"
+	if result.Item != "" {
+		message += " " + result.Item
+	}
+	if result.Line > 0 {
+		message += " (line " + strconv.Itoa(result.Line) + ": " + result.SourceLine + ")"
+	}
+	return message
+}
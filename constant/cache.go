@@ -3,4 +3,5 @@ package constant
 const (
 	CacheTypeMemory = "memory"
 	CacheTypeRedis  = "redis"
+	CacheTypeFile   = "file"
 )
@@ -6,4 +6,5 @@ const (
 	ConvertorTypeAdGuardRuleSet    = "adguard"
 	ConvertorTypeClashRuleProvider = "clash"
 	ConvertorTypeSurgeRuleSet      = "surge"
+	ConvertorTypeHostsFile         = "hosts"
 )
@@ -4,8 +4,18 @@ import "time"
 
 const DefaultTTL = 5 * time.Minute
 
+// Requested paths of endpoints and resources are refreshed in background,
+// up to RefreshPathCapacity paths each, until not requested for RefreshPathIdleTimeout.
+const (
+	RefreshPathCapacity    = 1024
+	RefreshPathIdleTimeout = 24 * time.Hour
+)
+
 const (
 	EndpointTypeFile     = "file"
+	EndpointTypeMerge    = "merge"
+	EndpointTypeMatch    = "match"
+	EndpointTypeReport   = "report"
 	EndpointSourceLocal  = "local"
 	EndpointSourceRemote = "remote"
 )
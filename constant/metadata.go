@@ -1,6 +1,7 @@
 package constant
 
 import (
+	"regexp"
 	"strings"
 
 	"github.com/sagernet/srsc/common/semver"
@@ -9,8 +10,17 @@ import (
 type Platform string
 
 const (
-	PlatformUnknown Platform = ""
-	PlatformSingBox Platform = "sing-box"
+	PlatformUnknown             Platform = ""
+	PlatformSingBox             Platform = "sing-box"
+	PlatformMihomo              Platform = "mihomo"
+	PlatformClashVerge          Platform = "clash-verge"
+	PlatformClashMetaForAndroid Platform = "clash-meta-for-android"
+	PlatformClash               Platform = "clash"
+	PlatformStash               Platform = "stash"
+	PlatformSurge               Platform = "surge"
+	PlatformLoon                Platform = "loon"
+	PlatformShadowrocket        Platform = "shadowrocket"
+	PlatformQuantumultX         Platform = "quantumult-x"
 )
 
 type System string
@@ -51,10 +61,76 @@ func DetectMetadata(userAgent string) Metadata {
 		} else {
 			versionName = strings.Split(versionName, ")")[0]
 		}
+	} else {
+		metadata.Platform, versionName = detectProduct(userAgent)
+		versionName = versionRegex.FindString(versionName)
 	}
+	if metadata.System == SystemUnknown {
+		metadata.System = detectSystem(userAgent)
+	}
+	versionName = strings.TrimPrefix(versionName, "v")
 	if semver.IsValid(versionName) {
 		version := semver.ParseVersion(versionName)
 		metadata.Version = &version
 	}
 	return metadata
 }
+
+// productPlatforms maps product names in user agents to platforms,
+// ordered so that products reporting other products they are compatible with are matched first.
+var productPlatforms = []struct {
+	product  string
+	platform Platform
+}{
+	{"Stash", PlatformStash},
+	{"clash-verge", PlatformClashVerge},
+	{"mihomo", PlatformMihomo},
+	{"clash.meta", PlatformMihomo},
+	{"ClashMetaForAndroid", PlatformClashMetaForAndroid},
+	{"Surge iOS", PlatformSurge},
+	{"Surge Mac", PlatformSurge},
+	{"Surge", PlatformSurge},
+	{"Loon", PlatformLoon},
+	{"Shadowrocket", PlatformShadowrocket},
+	{"Quantumult%20X", PlatformQuantumultX},
+	{"Quantumult X", PlatformQuantumultX},
+	{"ClashX", PlatformClash},
+	{"ClashForWindows", PlatformClash},
+	{"ClashForAndroid", PlatformClash},
+	{"clash", PlatformClash},
+}
+
+// versionRegex matches the leading version of a product version, such as `1.18.3` of `v1.18.3` or `2.10.1` of `2.10.1.Meta`.
+// Surge, Loon and Shadowrocket report build numbers as versions.
+var versionRegex = regexp.MustCompile(`^v?\d+(\.\d+){0,2}(-[0-9A-Za-z.]+)?`)
+
+func detectProduct(userAgent string) (Platform, string) {
+	lowerUserAgent := strings.ToLower(userAgent)
+	for _, product := range productPlatforms {
+		index := strings.Index(lowerUserAgent, strings.ToLower(product.product)+"/")
+		if index == -1 {
+			continue
+		}
+		versionName := userAgent[index+len(product.product)+1:]
+		if end := strings.IndexAny(versionName, " ;)"); end != -1 {
+			versionName = versionName[:end]
+		}
+		return product.platform, versionName
+	}
+	return PlatformUnknown, ""
+}
+
+func detectSystem(userAgent string) System {
+	switch {
+	case strings.Contains(userAgent, "Surge iOS/"), strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"), strings.Contains(userAgent, "iOS"):
+		return SystemiOS
+	case strings.Contains(userAgent, "Surge Mac/"), strings.Contains(userAgent, "Macintosh"), strings.Contains(userAgent, "macOS"):
+		return SystemMacOS
+	case strings.Contains(userAgent, "AppleTV"), strings.Contains(userAgent, "tvOS"):
+		return SystemAppleTVOS
+	case strings.Contains(userAgent, "Android"), strings.Contains(userAgent, "ClashMetaForAndroid/"), strings.Contains(userAgent, "ClashForAndroid/"):
+		return SystemAndroid
+	default:
+		return SystemUnknown
+	}
+}
@@ -0,0 +1,52 @@
+package constant
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectMetadata(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		userAgent string
+		platform  Platform
+		system    System
+		version   string
+	}{
+		{"SFA/1.10.0 (442; sing-box 1.10.0; language zh_CN)", PlatformSingBox, SystemAndroid, "1.10.0"},
+		{"SFI/1.11.0 (Build 1; sing-box 1.11.0-beta.5; language en_US)", PlatformSingBox, SystemiOS, "1.11.0-beta.5"},
+		{"SFM/1.9.0 (sing-box 1.9.0)", PlatformSingBox, SystemMacOS, "1.9.0"},
+		{"SFT/1.12.0 (sing-box 1.12.0)", PlatformSingBox, SystemAppleTVOS, "1.12.0"},
+		{"sing-box 1.8.0", PlatformSingBox, SystemUnknown, "1.8.0"},
+		{"mihomo/1.18.3", PlatformMihomo, SystemUnknown, "1.18.3"},
+		{"clash.meta/v1.17.0", PlatformMihomo, SystemUnknown, "1.17.0"},
+		{"ClashMetaForAndroid/2.10.1.Meta", PlatformClashMetaForAndroid, SystemAndroid, "2.10.1"},
+		{"clash-verge/v1.3.8", PlatformClashVerge, SystemUnknown, "1.3.8"},
+		{"Stash/2.4.6 Clash/1.9.0", PlatformStash, SystemUnknown, "2.4.6"},
+		{"Surge iOS/2920", PlatformSurge, SystemiOS, "2920.0.0"},
+		{"Surge Mac/2638", PlatformSurge, SystemMacOS, "2638.0.0"},
+		{"Loon/753 CFNetwork/1494.0.7 Darwin/23.4.0", PlatformLoon, SystemUnknown, "753.0.0"},
+		{"Shadowrocket/2070 CFNetwork/1494.0.7 Darwin/23.4.0 iPhone14,2", PlatformShadowrocket, SystemiOS, "2070.0.0"},
+		{"ClashX/1.118.0 (com.west2online.ClashX; build:1.118.0; macOS 14.4.1) Alamofire/5.8.0", PlatformClash, SystemMacOS, "1.118.0"},
+		{"ClashforWindows/0.20.39", PlatformClash, SystemUnknown, "0.20.39"},
+		{"Quantumult%20X/1.4.1 (iPhone14,2; iOS 17.4)", PlatformQuantumultX, SystemiOS, "1.4.1"},
+		{"Quantumult X/1.0.30 (iPad13,4; iOS 16.1)", PlatformQuantumultX, SystemiOS, "1.0.30"},
+		{"curl/8.4.0", PlatformUnknown, SystemUnknown, ""},
+		{"", PlatformUnknown, SystemUnknown, ""},
+	} {
+		t.Run(testCase.userAgent, func(t *testing.T) {
+			t.Parallel()
+			metadata := DetectMetadata(testCase.userAgent)
+			require.Equal(t, testCase.userAgent, metadata.UserAgent)
+			require.Equal(t, testCase.platform, metadata.Platform)
+			require.Equal(t, testCase.system, metadata.System)
+			if testCase.version == "" {
+				require.Nil(t, metadata.Version)
+			} else {
+				require.NotNil(t, metadata.Version)
+				require.Equal(t, testCase.version, metadata.Version.String())
+			}
+		})
+	}
+}
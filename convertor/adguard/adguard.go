@@ -26,9 +26,9 @@ func (a *RuleSet) From(ctx context.Context, content []byte, options adapter.Conv
 	if options.Options.AdGuardOptions.AcceptExtendedRules && options.Options.TargetType != C.ConvertorTypeAdGuardRuleSet && options.Options.TargetType != C.ConvertorTypeRuleSetBinary {
 		return nil, E.New("AdGuard rule-set can only be converted to sing-box rule-set binary when `accept_extended_rules` enabled")
 	}
-	return ToRules(bytes.NewReader(content), options.Options.AdGuardOptions.AcceptExtendedRules, logger.NOP())
+	return toRules(bytes.NewReader(content), options.Options.AdGuardOptions.AcceptExtendedRules, logger.NOP(), options)
 }
 
 func (a *RuleSet) To(ctx context.Context, contentRules []adapter.Rule, options adapter.ConvertOptions) ([]byte, error) {
-	return FromRules(contentRules)
+	return fromRules(contentRules, options)
 }
@@ -13,6 +13,7 @@ import (
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
 	"github.com/sagernet/sing/common/logger"
 	M "github.com/sagernet/sing/common/metadata"
 	"github.com/sagernet/srsc/adapter"
@@ -31,11 +32,23 @@ type adguardRuleLine struct {
 }
 
 func ToRules(reader io.Reader, acceptExtendedRules bool, logger logger.Logger) ([]adapter.Rule, error) {
+	return toRules(reader, acceptExtendedRules, logger, adapter.ConvertOptions{})
+}
+
+func toRules(reader io.Reader, acceptExtendedRules bool, logger logger.Logger, options adapter.ConvertOptions) ([]adapter.Rule, error) {
 	scanner := bufio.NewScanner(reader)
 	var (
 		ruleLines    []adguardRuleLine
 		ignoredLines int
+		dropErr      error
 	)
+	ignoreLine := func(ruleLine string, reason ...any) {
+		ignoredLines++
+		logger.Debug("ignored ", F.ToString(reason...), ": ", ruleLine)
+		if dropErr == nil {
+			dropErr = options.DropLine(ruleLine, reason...)
+		}
+	}
 parseLine:
 	for scanner.Scan() {
 		ruleLine := scanner.Text()
@@ -97,8 +110,7 @@ parseLine:
 					}
 				}
 				if !ignored {
-					ignoredLines++
-					logger.Debug("ignored unsupported rule with modifier: ", paramParts[0], ": ", originRuleLine)
+					ignoreLine(originRuleLine, "unsupported rule with modifier: ", paramParts[0])
 					continue parseLine
 				}
 			}
@@ -125,8 +137,7 @@ parseLine:
 		if strings.HasPrefix(ruleLine, "/") && strings.HasSuffix(ruleLine, "/") {
 			ruleLine = ruleLine[1 : len(ruleLine)-1]
 			if ignoreIPCIDRRegexp(ruleLine) {
-				ignoredLines++
-				logger.Debug("ignored unsupported rule with IPCIDR regexp: ", originRuleLine)
+				ignoreLine(originRuleLine, "unsupported rule with IPCIDR regexp")
 				continue
 			}
 			isRegexp = true
@@ -136,25 +147,21 @@ parseLine:
 				isSuffix = true
 			}
 			if strings.Contains(ruleLine, "/") {
-				ignoredLines++
-				logger.Debug("ignored unsupported rule with path: ", originRuleLine)
+				ignoreLine(originRuleLine, "unsupported rule with path")
 				continue
 			}
 			if strings.Contains(ruleLine, "?") || strings.Contains(ruleLine, "&") {
-				ignoredLines++
-				logger.Debug("ignored unsupported rule with query: ", originRuleLine)
+				ignoreLine(originRuleLine, "unsupported rule with query")
 				continue
 			}
 			if strings.Contains(ruleLine, "[") || strings.Contains(ruleLine, "]") ||
 				strings.Contains(ruleLine, "(") || strings.Contains(ruleLine, ")") ||
 				strings.Contains(ruleLine, "!") || strings.Contains(ruleLine, "#") {
-				ignoredLines++
-				logger.Debug("ignored unsupported cosmetic filter: ", originRuleLine)
+				ignoreLine(originRuleLine, "unsupported cosmetic filter")
 				continue
 			}
 			if strings.Contains(ruleLine, "~") {
-				ignoredLines++
-				logger.Debug("ignored unsupported rule modifier: ", originRuleLine)
+				ignoreLine(originRuleLine, "unsupported rule modifier")
 				continue
 			}
 			var domainCheck string
@@ -164,24 +171,21 @@ parseLine:
 				domainCheck = ruleLine
 			}
 			if ruleLine == "" {
-				ignoredLines++
-				logger.Debug("ignored unsupported rule with empty domain", originRuleLine)
+				ignoreLine(originRuleLine, "unsupported rule with empty domain")
 				continue
 			} else {
 				domainCheck = strings.ReplaceAll(domainCheck, "*", "x")
 				if !M.IsDomainName(domainCheck) {
 					_, ipErr := parseADGuardIPCIDRLine(ruleLine)
 					if ipErr == nil {
-						ignoredLines++
-						logger.Debug("ignored unsupported rule with IPCIDR: ", originRuleLine)
+						ignoreLine(originRuleLine, "unsupported rule with IPCIDR")
 						continue
 					}
 					if M.ParseSocksaddr(domainCheck).Port != 0 {
-						logger.Debug("ignored unsupported rule with port: ", originRuleLine)
+						ignoreLine(originRuleLine, "unsupported rule with port")
 					} else {
-						logger.Debug("ignored unsupported rule with invalid domain: ", originRuleLine)
+						ignoreLine(originRuleLine, "unsupported rule with invalid domain")
 					}
-					ignoredLines++
 					continue
 				}
 			}
@@ -197,6 +201,9 @@ parseLine:
 			isImportant:    isImportant,
 		})
 	}
+	if dropErr != nil {
+		return nil, dropErr
+	}
 	if len(ruleLines) == 0 {
 		return nil, E.New("AdGuard rule-set is empty or all rules are unsupported")
 	}
@@ -317,17 +324,18 @@ parseLine:
 				originRuleLine = it.ruleLine
 			}
 			if !it.hasEnd {
-				logger.Debug("ignored extended rule without end: ", originRuleLine)
-				ignoredLines++
+				ignoreLine(originRuleLine, "extended rule without end")
 				return false
 			}
 			if !it.hasStart && !it.isSuffix {
-				logger.Debug("ignored extended rule without start: ", originRuleLine)
-				ignoredLines++
+				ignoreLine(originRuleLine, "extended rule without start")
 				return false
 			}
 			return true
 		})
+		if dropErr != nil {
+			return nil, dropErr
+		}
 		mapDomain := func(it adguardRuleLine) string { return it.ruleLine }
 		importantDomain := common.Map(common.Filter(ruleLines, func(it adguardRuleLine) bool { return it.isImportant && !it.isRegexp && !it.isExclude && !it.isSuffix }), mapDomain)
 		importantDomainSuffix := common.Map(common.Filter(ruleLines, func(it adguardRuleLine) bool { return it.isImportant && !it.isRegexp && !it.isExclude && it.isSuffix }), mapDomain)
@@ -424,9 +432,18 @@ parseLine:
 }
 
 func FromRules(rules []adapter.Rule) ([]byte, error) {
+	return fromRules(rules, adapter.ConvertOptions{})
+}
+
+func fromRules(rules []adapter.Rule, options adapter.ConvertOptions) ([]byte, error) {
 	var buffer bytes.Buffer
 	for _, rule := range rules {
-		FromRule(rule, &buffer)
+		if !FromRule(rule, &buffer) {
+			err := options.DropRule(rule, "unsupported by AdGuard rule-set")
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 	if buffer.Len() > 0 {
 		return buffer.Bytes(), nil
@@ -435,7 +452,8 @@ func FromRules(rules []adapter.Rule) ([]byte, error) {
 	}
 }
 
-func FromRule(rule adapter.Rule, output *bytes.Buffer) {
+// FromRule writes rule in AdGuard format to output and reports whether the rule is supported.
+func FromRule(rule adapter.Rule, output *bytes.Buffer) bool {
 	var (
 		importantDomainAdGuard        []string
 		importantDomain               []string
@@ -459,7 +477,7 @@ parse:
 		switch rule.Type {
 		case C.RuleTypeLogical:
 			if !(len(rule.LogicalOptions.Rules) == 2 && rule.LogicalOptions.Rules[0].Type == C.RuleTypeDefault && adapter.IsDestinationAddressRule(rule.LogicalOptions.Rules[0].DefaultOptions)) {
-				return
+				return false
 			}
 			if rule.LogicalOptions.Mode == C.LogicalTypeAnd && rule.LogicalOptions.Rules[0].DefaultOptions.Invert {
 				if len(importantExcludeDomainAdGuard) == 0 && len(importantExcludeDomainRegex) == 0 {
@@ -479,12 +497,12 @@ parse:
 				importantDomainSuffix = rule.LogicalOptions.Rules[0].DefaultOptions.DomainSuffix
 				importantDomainRegex = rule.LogicalOptions.Rules[0].DefaultOptions.DomainRegex
 			} else {
-				return
+				return false
 			}
 			rule = rule.LogicalOptions.Rules[1]
 		case C.RuleTypeDefault:
 			if !adapter.IsDestinationAddressRule(rule.DefaultOptions) {
-				return
+				return false
 			}
 			domainAdGuard = rule.DefaultOptions.AdGuardDomain
 			domain = rule.DefaultOptions.Domain
@@ -571,6 +589,7 @@ parse:
 		output.WriteString(ruleLine)
 		output.WriteString("/\n")
 	}
+	return true
 }
 
 func ignoreIPCIDRRegexp(ruleLine string) bool {
@@ -47,16 +47,27 @@ func (s *RuleSetBinary) To(ctx context.Context, contentRules []adapter.Rule, opt
 		}
 	}
 
+	rules, err := headlessRules(convertedRules, options)
+	if err != nil {
+		return nil, err
+	}
 	ruleSet := &option.PlainRuleSetCompat{
 		Version: boxConstant.RuleSetVersionCurrent,
 		Options: option.PlainRuleSet{
-			Rules: common.Map(common.Filter(convertedRules, func(it adapter.Rule) bool {
-				return it.Headlessable()
-			}), adapter.Rule.ToHeadless),
+			Rules: rules,
 		},
 	}
+	if options.Metadata.Platform == C.PlatformSingBox && options.Options.FilterSystemItems && options.Metadata.System != C.SystemUnknown {
+		err = FilterSystem(ruleSet, options.Metadata.System, options)
+		if err != nil {
+			return nil, err
+		}
+	}
 	if options.Metadata.Platform == C.PlatformSingBox && options.Metadata.Version != nil {
-		Downgrade(ruleSet, options.Metadata.Version)
+		err = Downgrade(ruleSet, options.Metadata.Version, options)
+		if err != nil {
+			return nil, err
+		}
 	}
 	buffer := new(bytes.Buffer)
 	err = srs.Write(buffer, ruleSet.Options, ruleSet.Version)
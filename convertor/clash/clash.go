@@ -4,10 +4,12 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"net/netip"
 	"reflect"
 	"strings"
 
 	boxConstant "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/srsc/adapter"
 	C "github.com/sagernet/srsc/constant"
@@ -24,7 +26,7 @@ func (c *RuleProvider) Type() string {
 }
 
 func (c *RuleProvider) ContentType(options adapter.ConvertOptions) string {
-	switch options.Options.TargetConvertOptions.ClashOptions.TargetFormat {
+	switch TargetFormat(options) {
 	case "yaml":
 		return "application/x-yaml"
 	case "mrs":
@@ -61,12 +63,18 @@ func (c *RuleProvider) From(ctx context.Context, content []byte, options adapter
 		var rule adapter.DefaultRule
 		if len(lines) > 0 {
 			for _, line := range lines {
-				fromDomainLine(&rule, line)
+				err := fromDomainLine(&rule, line, options)
+				if err != nil {
+					return nil, err
+				}
 			}
 		} else {
 			scanner := bufio.NewScanner(bytes.NewReader(content))
 			for scanner.Scan() {
-				fromDomainLine(&rule, scanner.Text())
+				err := fromDomainLine(&rule, scanner.Text(), options)
+				if err != nil {
+					return nil, err
+				}
 			}
 		}
 		return []adapter.Rule{{Type: boxConstant.RuleTypeDefault, DefaultOptions: rule}}, nil
@@ -74,30 +82,39 @@ func (c *RuleProvider) From(ctx context.Context, content []byte, options adapter
 		var rule adapter.DefaultRule
 		if len(lines) > 0 {
 			for _, line := range lines {
-				fromIPCIDRLine(&rule, line)
+				err := fromIPCIDRLine(&rule, line, options)
+				if err != nil {
+					return nil, err
+				}
 			}
 		} else {
 			scanner := bufio.NewScanner(bytes.NewReader(content))
 			for scanner.Scan() {
-				fromIPCIDRLine(&rule, scanner.Text())
+				err := fromIPCIDRLine(&rule, scanner.Text(), options)
+				if err != nil {
+					return nil, err
+				}
 			}
 		}
 		return []adapter.Rule{{Type: boxConstant.RuleTypeDefault, DefaultOptions: rule}}, nil
 	case "classical":
-		var rules []adapter.Rule
+		var (
+			rules []adapter.Rule
+			err   error
+		)
 		if len(lines) > 0 {
 			for _, line := range lines {
-				rule, _ := fromClassicalLine(line)
-				if rule != nil {
-					rules = append(rules, *rule)
+				rules, err = appendClassicalLine(rules, line, options)
+				if err != nil {
+					return nil, err
 				}
 			}
 		} else {
 			scanner := bufio.NewScanner(bytes.NewReader(content))
 			for scanner.Scan() {
-				rule, _ := fromClassicalLine(scanner.Text())
-				if rule != nil {
-					rules = append(rules, *rule)
+				rules, err = appendClassicalLine(rules, scanner.Text(), options)
+				if err != nil {
+					return nil, err
 				}
 			}
 		}
@@ -114,12 +131,12 @@ func (c *RuleProvider) To(ctx context.Context, contentRules []adapter.Rule, opti
 	if err != nil {
 		return nil, err
 	}
-	format := options.Options.TargetConvertOptions.ClashOptions.TargetFormat
+	format := TargetFormat(options)
 	behavior := options.Options.TargetConvertOptions.ClashOptions.TargetBehavior
 	if format == "mrs" {
-		return toMrs(behavior, convertedRules)
+		return toMrs(behavior, convertedRules, options)
 	}
-	ruleLines, err := toLines(behavior, convertedRules)
+	ruleLines, err := toLines(behavior, convertedRules, options)
 	if err != nil {
 		return nil, err
 	}
@@ -151,40 +168,60 @@ func (c *RuleProvider) To(ctx context.Context, contentRules []adapter.Rule, opti
 	}
 }
 
-func fromDomainLine(rule *adapter.DefaultRule, ruleLine string) {
+func fromDomainLine(rule *adapter.DefaultRule, ruleLine string, options adapter.ConvertOptions) error {
 	if ruleLine == "" || strings.HasPrefix(ruleLine, "#") {
-		return
+		return nil
 	}
+	originRuleLine := ruleLine
 	var domainSuffix bool
 	if strings.HasPrefix(ruleLine, "+.") {
 		domainSuffix = true
 		ruleLine = strings.TrimPrefix(ruleLine, "+.")
 	}
 	if strings.Contains(ruleLine, "+") || strings.Contains(ruleLine, "*") {
-		return
+		return options.DropLine(originRuleLine, "unsupported wildcard domain")
 	}
 	if domainSuffix {
 		rule.DomainSuffix = append(rule.DomainSuffix, ruleLine)
 	} else {
 		rule.Domain = append(rule.Domain, ruleLine)
 	}
+	return nil
+}
+
+func appendClassicalLine(rules []adapter.Rule, ruleLine string, options adapter.ConvertOptions) ([]adapter.Rule, error) {
+	ruleLine = strings.TrimSpace(ruleLine)
+	if ruleLine == "" || strings.HasPrefix(ruleLine, "#") {
+		return rules, nil
+	}
+	rule, err := fromClassicalLine(ruleLine)
+	if err != nil {
+		return rules, options.DropLine(ruleLine, err)
+	}
+	return append(rules, *rule), nil
 }
 
-func fromIPCIDRLine(rule *adapter.DefaultRule, ruleLine string) {
+func fromIPCIDRLine(rule *adapter.DefaultRule, ruleLine string, options adapter.ConvertOptions) error {
 	if ruleLine == "" || strings.HasPrefix(ruleLine, "#") {
-		return
+		return nil
+	}
+	_, err := netip.ParsePrefix(ruleLine)
+	if err != nil && common.Error(netip.ParseAddr(ruleLine)) != nil {
+		return options.DropLine(ruleLine, "invalid IP CIDR")
 	}
 	rule.IPCIDR = append(rule.IPCIDR, ruleLine)
+	return nil
 }
 
-func toLines(behavior string, rules []adapter.Rule) ([]string, error) {
+func toLines(behavior string, rules []adapter.Rule, options adapter.ConvertOptions) ([]string, error) {
 	var lines []string
 	switch behavior {
 	case "domain":
-		for _, rule := range rules {
-			if rule.Type != boxConstant.RuleTypeDefault || !adapter.IsDestinationAddressRule(rule.DefaultOptions) {
-				continue
-			}
+		behaviorRules, err := FilterBehaviorRules(behavior, rules, options)
+		if err != nil {
+			return nil, err
+		}
+		for _, rule := range behaviorRules {
 			for _, domain := range rule.DefaultOptions.Domain {
 				lines = append(lines, domain)
 			}
@@ -194,26 +231,106 @@ func toLines(behavior string, rules []adapter.Rule) ([]string, error) {
 		}
 		return lines, nil
 	case "ipcidr":
-		for _, rule := range rules {
-			if rule.Type != boxConstant.RuleTypeDefault || !adapter.IsDestinationAddressRule(rule.DefaultOptions) {
-				continue
-			}
+		behaviorRules, err := FilterBehaviorRules(behavior, rules, options)
+		if err != nil {
+			return nil, err
+		}
+		for _, rule := range behaviorRules {
 			for _, ipCidr := range rule.DefaultOptions.IPCIDR {
 				lines = append(lines, ipCidr)
 			}
 		}
 	case "classical":
+		profile := detectClient(options.Metadata)
 		for _, rule := range rules {
-			ruleLines, err := toClassicalLine(rule)
-			if err == nil {
+			// lines of a default rule are matched independently, so lines of unsupported rule types are dropped alone
+			independentLines := rule.Type == boxConstant.RuleTypeDefault && !rule.DefaultOptions.Invert
+			var (
+				ruleLines []string
+				err       error
+			)
+			if independentLines {
+				ruleLines, err = toClassicalLine(rule, nil)
+			} else {
+				ruleLines, err = toClassicalLine(rule, profile)
+			}
+			if err != nil {
+				err = options.DropRule(rule, err)
+				if err != nil {
+					return nil, err
+				}
 				continue
 			}
+			if independentLines {
+				ruleLines, err = filterClassicalLines(ruleLines, profile, options)
+				if err != nil {
+					return nil, err
+				}
+			}
 			lines = append(lines, ruleLines...)
 		}
 	}
 	return lines, nil
 }
 
+// filterClassicalLines removes lines of rule types unsupported by the client, which are recorded as dropped.
+func filterClassicalLines(lines []string, profile *clientProfile, options adapter.ConvertOptions) ([]string, error) {
+	var filteredLines []string
+	for _, line := range lines {
+		ruleType, _, _ := strings.Cut(line, ",")
+		reason := profile.unsupportedRuleType(ruleType)
+		if reason == "" {
+			filteredLines = append(filteredLines, line)
+			continue
+		}
+		var err error
+		if droppedRule, parseErr := fromClassicalLine(line); parseErr == nil {
+			err = options.DropRule(*droppedRule, "`", ruleType, "` ", reason)
+		} else {
+			err = options.DropLine(line, "`", ruleType, "` ", reason)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return filteredLines, nil
+}
+
+// FilterBehaviorRules returns destination address rules usable by domain or ipcidr behavior,
+// other rules and items unsupported by the behavior are recorded as dropped.
+func FilterBehaviorRules(behavior string, rules []adapter.Rule, options adapter.ConvertOptions) ([]adapter.Rule, error) {
+	var behaviorRules []adapter.Rule
+	for _, rule := range rules {
+		if rule.Type != boxConstant.RuleTypeDefault || !adapter.IsDestinationAddressRule(rule.DefaultOptions) {
+			err := options.DropRule(rule, "unsupported by ", behavior, " behavior")
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		var droppedRule adapter.DefaultRule
+		if behavior == "domain" {
+			droppedRule.IPCIDR = rule.DefaultOptions.IPCIDR
+		} else {
+			droppedRule.Domain = rule.DefaultOptions.Domain
+			droppedRule.DomainSuffix = rule.DefaultOptions.DomainSuffix
+		}
+		droppedRule.DomainKeyword = rule.DefaultOptions.DomainKeyword
+		droppedRule.DomainRegex = rule.DefaultOptions.DomainRegex
+		droppedRule.GEOIP = rule.DefaultOptions.GEOIP
+		droppedRule.IPASN = rule.DefaultOptions.IPASN
+		if len(droppedRule.Domain) > 0 || len(droppedRule.DomainSuffix) > 0 || len(droppedRule.IPCIDR) > 0 ||
+			len(droppedRule.DomainKeyword) > 0 || len(droppedRule.DomainRegex) > 0 || len(droppedRule.GEOIP) > 0 || len(droppedRule.IPASN) > 0 {
+			err := options.DropRule(adapter.Rule{Type: boxConstant.RuleTypeDefault, DefaultOptions: droppedRule}, "items unsupported by ", behavior, " behavior")
+			if err != nil {
+				return nil, err
+			}
+		}
+		behaviorRules = append(behaviorRules, rule)
+	}
+	return behaviorRules, nil
+}
+
 func IsSimpleDomainRule(rule adapter.DefaultRule) bool {
 	var defaultRule adapter.DefaultRule
 	defaultRule.Domain = rule.Domain
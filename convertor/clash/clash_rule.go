@@ -18,11 +18,11 @@ import (
 	"golang.org/x/exp/slices"
 )
 
-func toClassicalLine(rule adapter.Rule) ([]string, error) {
+func toClassicalLine(rule adapter.Rule, profile *clientProfile) ([]string, error) {
 	if rule.Type == C.RuleTypeLogical {
 		var subRules []string
 		for _, subRule := range rule.LogicalOptions.Rules {
-			subRuleLines, err := toClassicalLine(subRule)
+			subRuleLines, err := toClassicalLine(subRule, profile)
 			if err != nil {
 				return nil, err
 			}
@@ -43,7 +43,7 @@ func toClassicalLine(rule adapter.Rule) ([]string, error) {
 		}
 	} else if rule.DefaultOptions.Invert {
 		rule.DefaultOptions.Invert = false
-		invertLines, err := toClassicalLine(rule)
+		invertLines, err := toClassicalLine(rule, profile)
 		if err != nil {
 			return nil, err
 		}
@@ -152,6 +152,12 @@ func toClassicalLine(rule adapter.Rule) ([]string, error) {
 		for _, inboundUser := range rule.DefaultOptions.InboundUser {
 			lines = append(lines, "IN-USER,"+inboundUser)
 		}
+		for _, line := range lines {
+			ruleType, _, _ := strings.Cut(line, ",")
+			if reason := profile.unsupportedRuleType(ruleType); reason != "" {
+				return nil, E.New("`", ruleType, "` ", reason)
+			}
+		}
 		return lines, nil
 	}
 }
@@ -181,11 +187,11 @@ func fromClassicalLine(ruleLine string) (*adapter.Rule, error) {
 		rule.SourceIPCIDR = append(rule.SourceIPCIDR, payload)
 	case "SRC-PORT":
 		portRanges, err := utils.NewUnsignedRanges[uint16](payload)
-		if err == nil {
+		if err != nil {
 			return nil, err
 		}
 		for _, portRange := range portRanges {
-			if portRanges[0].Start() == portRanges[0].End() {
+			if portRange.Start() == portRange.End() {
 				rule.SourcePort = append(rule.SourcePort, portRange.Start())
 			} else {
 				rule.SourcePortRange = append(rule.SourcePortRange, F.ToString(portRange.Start(), ":", portRange.End()))
@@ -193,11 +199,11 @@ func fromClassicalLine(ruleLine string) (*adapter.Rule, error) {
 		}
 	case "DST-PORT":
 		portRanges, err := utils.NewUnsignedRanges[uint16](payload)
-		if err == nil {
+		if err != nil {
 			return nil, err
 		}
 		for _, portRange := range portRanges {
-			if portRanges[0].Start() == portRanges[0].End() {
+			if portRange.Start() == portRange.End() {
 				rule.Port = append(rule.Port, portRange.Start())
 			} else {
 				rule.PortRange = append(rule.PortRange, F.ToString(portRange.Start(), ":", portRange.End()))
@@ -235,7 +241,7 @@ func fromClassicalLine(ruleLine string) (*adapter.Rule, error) {
 		rule.InboundType = append(rule.InboundType, payload)
 	case "IN-PORT":
 		portRanges, err := utils.NewUnsignedRanges[uint16](payload)
-		if err == nil {
+		if err != nil {
 			return nil, err
 		}
 		for _, portRange := range portRanges {
@@ -0,0 +1,133 @@
+package clash
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sagernet/sing/service"
+	"github.com/sagernet/srsc/adapter"
+	C "github.com/sagernet/srsc/constant"
+	"github.com/sagernet/srsc/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testResourceManager has no resource configured.
+type testResourceManager struct {
+	adapter.ResourceManager
+}
+
+func (m *testResourceManager) GEOIPConfigured() bool {
+	return false
+}
+
+func (m *testResourceManager) GEOSiteConfigured() bool {
+	return false
+}
+
+func (m *testResourceManager) IPASNConfigured() bool {
+	return false
+}
+
+func TestRuleProviderToClient(t *testing.T) {
+	t.Parallel()
+	ctx := service.ContextWithDefaultRegistry(context.Background())
+	service.MustRegister[adapter.ResourceManager](ctx, &testResourceManager{})
+	var sourceOptions option.SourceConvertOptions
+	sourceOptions.ClashOptions.SourceFormat = "text"
+	sourceOptions.ClashOptions.SourceBehavior = "classical"
+	rules, err := (&RuleProvider{}).From(ctx, []byte("DOMAIN,a.com\nIN-NAME,mixed\nIP-ASN,13335\nDOMAIN-REGEX,^b\\.com$\nPROCESS-PATH-REGEX,^/usr/bin/\nGEOSITE,cn\nSRC-GEOIP,cn\n"), adapter.ConvertOptions{
+		Options: option.ConvertOptions{SourceConvertOptions: sourceOptions},
+	})
+	require.NoError(t, err)
+	allLines := []string{"DOMAIN,a.com", "DOMAIN-REGEX,^b\\.com$", "IP-ASN,13335", "IN-NAME,mixed", "PROCESS-PATH-REGEX,^/usr/bin/", "GEOSITE,cn", "SRC-GEOIP,cn"}
+	for _, testCase := range []struct {
+		userAgent string
+		lines     []string
+		reasons   []string
+	}{
+		{
+			userAgent: "mihomo/1.14.0",
+			lines:     []string{"DOMAIN,a.com", "GEOSITE,cn", "SRC-GEOIP,cn"},
+			reasons: []string{
+				"`DOMAIN-REGEX` unsupported by mihomo < 1.18.0",
+				"`IP-ASN` unsupported by mihomo < 1.17.0",
+				"`IN-NAME` unsupported by mihomo < 1.15.0",
+				"`PROCESS-PATH-REGEX` unsupported by mihomo < 1.18.0",
+			},
+		},
+		{
+			userAgent: "mihomo/1.15.0",
+			lines:     []string{"DOMAIN,a.com", "IN-NAME,mixed", "GEOSITE,cn", "SRC-GEOIP,cn"},
+			reasons: []string{
+				"`DOMAIN-REGEX` unsupported by mihomo < 1.18.0",
+				"`IP-ASN` unsupported by mihomo < 1.17.0",
+				"`PROCESS-PATH-REGEX` unsupported by mihomo < 1.18.0",
+			},
+		},
+		{
+			userAgent: "mihomo/1.17.0",
+			lines:     []string{"DOMAIN,a.com", "IP-ASN,13335", "IN-NAME,mixed", "GEOSITE,cn", "SRC-GEOIP,cn"},
+			reasons: []string{
+				"`DOMAIN-REGEX` unsupported by mihomo < 1.18.0",
+				"`PROCESS-PATH-REGEX` unsupported by mihomo < 1.18.0",
+			},
+		},
+		{userAgent: "mihomo/1.18.0", lines: allLines},
+		{userAgent: "mihomo/1.19.0", lines: allLines},
+		{userAgent: "mihomo", lines: allLines},
+		{userAgent: "clash-verge/v1.3.8", lines: allLines},
+		{
+			userAgent: "ClashX/1.118.0",
+			lines:     []string{"DOMAIN,a.com"},
+			reasons: []string{
+				"`DOMAIN-REGEX` unsupported by Clash",
+				"`IP-ASN` unsupported by Clash",
+				"`IN-NAME` unsupported by Clash",
+				"`PROCESS-PATH-REGEX` unsupported by Clash",
+				"`GEOSITE` unsupported by Clash",
+				"`SRC-GEOIP` unsupported by Clash",
+			},
+		},
+		{
+			userAgent: "Stash/2.4.6",
+			lines:     []string{"DOMAIN,a.com", "DOMAIN-REGEX,^b\\.com$", "IP-ASN,13335", "GEOSITE,cn"},
+			reasons: []string{
+				"`IN-NAME` unsupported by Stash",
+				"`PROCESS-PATH-REGEX` unsupported by Stash",
+				"`SRC-GEOIP` unsupported by Stash",
+			},
+		},
+	} {
+		t.Run(testCase.userAgent, func(t *testing.T) {
+			t.Parallel()
+			var targetOptions option.TargetConvertOptions
+			targetOptions.ClashOptions.TargetFormat = "text"
+			targetOptions.ClashOptions.TargetBehavior = "classical"
+			diagnostics := adapter.NewDiagnostics()
+			content, err := (&RuleProvider{}).To(ctx, rules, adapter.ConvertOptions{
+				Options:     option.ConvertOptions{TargetConvertOptions: targetOptions},
+				Metadata:    C.DetectMetadata(testCase.userAgent),
+				Diagnostics: diagnostics,
+			})
+			require.NoError(t, err)
+			require.Equal(t, strings.Join(testCase.lines, "\n")+"\n", string(content))
+			var reasons []string
+			for _, entry := range diagnostics.Entries() {
+				reasons = append(reasons, entry.Reason)
+			}
+			require.Equal(t, testCase.reasons, reasons)
+			targetOptions.Strict = true
+			_, err = (&RuleProvider{}).To(ctx, rules, adapter.ConvertOptions{
+				Options:  option.ConvertOptions{TargetConvertOptions: targetOptions},
+				Metadata: C.DetectMetadata(testCase.userAgent),
+			})
+			if len(testCase.reasons) > 0 {
+				require.ErrorContains(t, err, testCase.reasons[0])
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
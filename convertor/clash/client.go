@@ -0,0 +1,142 @@
+package clash
+
+import (
+	"github.com/sagernet/srsc/adapter"
+	"github.com/sagernet/srsc/common/semver"
+	C "github.com/sagernet/srsc/constant"
+)
+
+type mihomoFeature struct {
+	version   semver.Version
+	mrs       bool
+	ruleTypes []string
+}
+
+// mihomoFeatures lists the MRS format and rule types introduced by mihomo versions, ordered by version.
+var mihomoFeatures = []mihomoFeature{
+	{
+		version:   semver.ParseVersion("1.15.0"),
+		ruleTypes: []string{"IN-NAME", "IN-TYPE", "IN-USER"},
+	},
+	{
+		version:   semver.ParseVersion("1.17.0"),
+		ruleTypes: []string{"IP-ASN", "SRC-IP-ASN"},
+	},
+	{
+		version:   semver.ParseVersion("1.18.0"),
+		ruleTypes: []string{"DOMAIN-REGEX", "PROCESS-PATH-REGEX"},
+	},
+	{
+		version: semver.ParseVersion("1.18.6"),
+		mrs:     true,
+	},
+}
+
+// clashUnsupportedRuleTypes are rule types unsupported by Clash other than those introduced by mihomo.
+var clashUnsupportedRuleTypes = []string{"GEOSITE", "SRC-GEOIP"}
+
+// stashUnsupportedRuleTypes are mihomo rule types missing in Stash, see https://stash.wiki/rules/rule-types.
+var stashUnsupportedRuleTypes = []string{"IN-NAME", "IN-TYPE", "IN-USER", "IN-PORT", "SRC-IP-ASN", "SRC-GEOIP", "PROCESS-PATH-REGEX"}
+
+// clientProfile describes formats and rule types unsupported by the detected client.
+//
+// Methods are safe to call on a nil clientProfile, which supports everything.
+type clientProfile struct {
+	name      string
+	mrs       bool
+	ruleTypes map[string]string
+}
+
+func detectClient(metadata C.Metadata) *clientProfile {
+	switch metadata.Platform {
+	case C.PlatformMihomo:
+		if metadata.Version == nil {
+			return nil
+		}
+		var profile *clientProfile
+		for _, feature := range mihomoFeatures {
+			if metadata.Version.GreaterThanOrEqual(feature.version) {
+				continue
+			}
+			if profile == nil {
+				profile = &clientProfile{
+					name:      "mihomo<" + feature.version.String(),
+					mrs:       true,
+					ruleTypes: make(map[string]string),
+				}
+			}
+			if feature.mrs {
+				profile.mrs = false
+			}
+			for _, ruleType := range feature.ruleTypes {
+				profile.ruleTypes[ruleType] = "unsupported by mihomo < " + feature.version.String()
+			}
+		}
+		return profile
+	case C.PlatformClashVerge, C.PlatformClashMetaForAndroid:
+		// Clash Verge and ClashMetaForAndroid report the version of the app instead of the bundled mihomo core,
+		// which is kept up to date, so they are served as the latest mihomo.
+		return nil
+	case C.PlatformClash:
+		profile := &clientProfile{
+			name:      "clash",
+			ruleTypes: make(map[string]string),
+		}
+		for _, feature := range mihomoFeatures {
+			for _, ruleType := range feature.ruleTypes {
+				profile.ruleTypes[ruleType] = "unsupported by Clash"
+			}
+		}
+		for _, ruleType := range clashUnsupportedRuleTypes {
+			profile.ruleTypes[ruleType] = "unsupported by Clash"
+		}
+		return profile
+	case C.PlatformStash:
+		profile := &clientProfile{
+			name:      "stash",
+			ruleTypes: make(map[string]string),
+		}
+		for _, ruleType := range stashUnsupportedRuleTypes {
+			profile.ruleTypes[ruleType] = "unsupported by Stash"
+		}
+		return profile
+	default:
+		return nil
+	}
+}
+
+// unsupportedRuleType returns the reason if ruleType is unsupported by the client.
+func (p *clientProfile) unsupportedRuleType(ruleType string) string {
+	if p == nil {
+		return ""
+	}
+	return p.ruleTypes[ruleType]
+}
+
+// TargetFormat returns the format of rule providers converted for the client,
+// `mrs` falls back to `yaml` for clients that can't read MRS.
+func TargetFormat(options adapter.ConvertOptions) string {
+	format := options.Options.TargetConvertOptions.ClashOptions.TargetFormat
+	if format == "mrs" {
+		if profile := detectClient(options.Metadata); profile != nil && !profile.mrs {
+			return "yaml"
+		}
+	}
+	return format
+}
+
+// Variant returns the name of the variant of rule providers converted for the client,
+// or empty if the content is the same as converted for the latest mihomo.
+func Variant(options adapter.ConvertOptions) string {
+	profile := detectClient(options.Metadata)
+	if profile == nil {
+		return ""
+	}
+	if TargetFormat(options) != options.Options.TargetConvertOptions.ClashOptions.TargetFormat {
+		return profile.name
+	}
+	if options.Options.TargetConvertOptions.ClashOptions.TargetBehavior == "classical" && len(profile.ruleTypes) > 0 {
+		return profile.name
+	}
+	return ""
+}
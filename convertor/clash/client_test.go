@@ -0,0 +1,80 @@
+package clash
+
+import (
+	"testing"
+
+	"github.com/sagernet/srsc/adapter"
+	C "github.com/sagernet/srsc/constant"
+	"github.com/sagernet/srsc/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientVariant(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		userAgent string
+		format    string
+		behavior  string
+		target    string
+		variant   string
+	}{
+		{"mihomo/1.18.6", "mrs", "domain", "mrs", ""},
+		{"mihomo/1.18.5", "mrs", "domain", "yaml", "mihomo<1.18.6"},
+		{"mihomo/1.16.0", "text", "classical", "text", "mihomo<1.17.0"},
+		{"mihomo/1.18.0", "text", "domain", "text", ""},
+		{"clash-verge/v1.3.8", "mrs", "classical", "mrs", ""},
+		{"ClashMetaForAndroid/2.8.0.Meta", "mrs", "classical", "mrs", ""},
+		{"ClashX/1.118.0", "mrs", "domain", "yaml", "clash"},
+		{"ClashX/1.118.0", "yaml", "classical", "yaml", "clash"},
+		{"Stash/2.4.6", "mrs", "ipcidr", "yaml", "stash"},
+		{"Stash/2.4.6", "text", "classical", "text", "stash"},
+		{"Stash/2.4.6", "text", "domain", "text", ""},
+		{"curl/8.4.0", "mrs", "classical", "mrs", ""},
+	} {
+		t.Run(testCase.userAgent+" "+testCase.format+" "+testCase.behavior, func(t *testing.T) {
+			t.Parallel()
+			options := adapter.ConvertOptions{
+				Options: option.ConvertOptions{
+					TargetConvertOptions: option.TargetConvertOptions{
+						TargetType: C.ConvertorTypeClashRuleProvider,
+						ClashOptions: option.ClashRuleProviderTargetOptions{
+							TargetFormat:   testCase.format,
+							TargetBehavior: testCase.behavior,
+						},
+					},
+				},
+				Metadata: C.DetectMetadata(testCase.userAgent),
+			}
+			require.Equal(t, testCase.target, TargetFormat(options))
+			require.Equal(t, testCase.variant, Variant(options))
+		})
+	}
+}
+
+func TestClientRuleTypes(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		userAgent   string
+		supported   []string
+		unsupported []string
+	}{
+		{"mihomo/1.19.0", []string{"IN-NAME", "IP-ASN", "DOMAIN-REGEX", "GEOSITE"}, nil},
+		{"mihomo/1.17.0", []string{"IN-NAME", "IP-ASN"}, []string{"DOMAIN-REGEX", "PROCESS-PATH-REGEX"}},
+		{"clash-verge/v1.3.8", []string{"IN-NAME", "IP-ASN", "DOMAIN-REGEX"}, nil},
+		{"ClashMetaForAndroid/2.8.0.Meta", []string{"IN-NAME", "IP-ASN", "DOMAIN-REGEX", "PROCESS-PATH-REGEX"}, nil},
+		{"ClashX/1.118.0", []string{"DOMAIN", "GEOIP"}, []string{"IN-NAME", "IP-ASN", "DOMAIN-REGEX", "GEOSITE", "SRC-GEOIP"}},
+		{"Stash/2.4.6", []string{"DOMAIN", "IP-ASN", "GEOSITE"}, []string{"IN-NAME", "IN-PORT", "SRC-IP-ASN", "SRC-GEOIP", "PROCESS-PATH-REGEX"}},
+	} {
+		t.Run(testCase.userAgent, func(t *testing.T) {
+			t.Parallel()
+			profile := detectClient(C.DetectMetadata(testCase.userAgent))
+			for _, ruleType := range testCase.supported {
+				require.Empty(t, profile.unsupportedRuleType(ruleType), ruleType)
+			}
+			for _, ruleType := range testCase.unsupported {
+				require.NotEmpty(t, profile.unsupportedRuleType(ruleType), ruleType)
+			}
+		})
+	}
+}
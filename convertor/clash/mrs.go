@@ -102,27 +102,64 @@ func fromMrs(content []byte) ([]adapter.Rule, error) {
 	}
 }
 
-func toMrs(behavior string, rules []adapter.Rule) ([]byte, error) {
-	var output bytes.Buffer
-	encoder, err := zstd.NewWriter(&output, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+func toMrs(behavior string, rules []adapter.Rule, options adapter.ConvertOptions) ([]byte, error) {
+	rules, err := FilterBehaviorRules(behavior, rules, options)
 	if err != nil {
 		return nil, err
 	}
-	_, err = encoder.Write(MrsMagicBytes[:])
-	if err != nil {
-		return nil, err
+	dropItem := func(rule option.DefaultHeadlessRule, err error) error {
+		return options.DropRule(adapter.Rule{Type: C.RuleTypeDefault, DefaultOptions: adapter.DefaultRule{DefaultHeadlessRule: rule}}, err)
 	}
 	var ruleSize int64
+	domainTrie := trie.New[struct{}]()
+	ipCidrTrie := cidr.NewIpCidrSet()
 	for _, rule := range rules {
-		if rule.Type != C.RuleTypeDefault || !adapter.IsDestinationAddressRule(rule.DefaultOptions) {
-			continue
-		}
 		if behavior == "domain" {
-			ruleSize += int64(len(rule.DefaultOptions.Domain) + len(rule.DefaultOptions.DomainSuffix))
+			for _, domain := range rule.DefaultOptions.Domain {
+				err = domainTrie.Insert(domain, struct{}{})
+				if err != nil {
+					err = dropItem(option.DefaultHeadlessRule{Domain: []string{domain}}, err)
+					if err != nil {
+						return nil, err
+					}
+					continue
+				}
+				ruleSize++
+			}
+			for _, domainSuffix := range rule.DefaultOptions.DomainSuffix {
+				err = domainTrie.Insert("+."+domainSuffix, struct{}{})
+				if err != nil {
+					err = dropItem(option.DefaultHeadlessRule{DomainSuffix: []string{domainSuffix}}, err)
+					if err != nil {
+						return nil, err
+					}
+					continue
+				}
+				ruleSize++
+			}
 		} else {
-			ruleSize += int64(len(rule.DefaultOptions.IPCIDR))
+			for _, ipCidr := range rule.DefaultOptions.IPCIDR {
+				err = ipCidrTrie.AddIpCidrForString(ipCidr)
+				if err != nil {
+					err = dropItem(option.DefaultHeadlessRule{IPCIDR: []string{ipCidr}}, err)
+					if err != nil {
+						return nil, err
+					}
+					continue
+				}
+				ruleSize++
+			}
 		}
 	}
+	var output bytes.Buffer
+	encoder, err := zstd.NewWriter(&output, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+	if err != nil {
+		return nil, err
+	}
+	_, err = encoder.Write(MrsMagicBytes[:])
+	if err != nil {
+		return nil, err
+	}
 	if behavior == "domain" {
 		encoder.Write([]byte{0})
 	} else {
@@ -136,25 +173,6 @@ func toMrs(behavior string, rules []adapter.Rule) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	domainTrie := trie.New[struct{}]()
-	ipCidrTrie := cidr.NewIpCidrSet()
-	for _, rule := range rules {
-		if rule.Type != C.RuleTypeDefault || !adapter.IsDestinationAddressRule(rule.DefaultOptions) {
-			continue
-		}
-		if behavior == "domain" {
-			for _, domain := range rules[0].DefaultOptions.Domain {
-				domainTrie.Insert(domain, struct{}{})
-			}
-			for _, domainSuffix := range rules[0].DefaultOptions.DomainSuffix {
-				domainTrie.Insert("+."+domainSuffix, struct{}{})
-			}
-		} else {
-			for _, ipCidr := range rules[0].DefaultOptions.IPCIDR {
-				ipCidrTrie.AddIpCidrForString(ipCidr)
-			}
-		}
-	}
 	if behavior == "domain" {
 		domainSet := domainTrie.NewDomainSet()
 		if domainSet == nil {
@@ -1,6 +1,8 @@
 package convertor
 
 import (
+	"strings"
+
 	"github.com/sagernet/srsc/adapter"
 	C "github.com/sagernet/srsc/constant"
 	"github.com/sagernet/srsc/convertor/adguard"
@@ -13,4 +15,25 @@ var Convertors = map[string]adapter.Convertor{
 	C.ConvertorTypeAdGuardRuleSet:    (*adguard.RuleSet)(nil),
 	C.ConvertorTypeClashRuleProvider: (*clash.RuleProvider)(nil),
 	C.ConvertorTypeSurgeRuleSet:      (*SurgeRuleSet)(nil),
+	C.ConvertorTypeHostsFile:         (*HostsFile)(nil),
+}
+
+// Variant returns the name of the variant of content converted with options for the detected client,
+// or empty if the content is not tailored to the client. Different variants should be cached separately.
+func Variant(options adapter.ConvertOptions) string {
+	switch options.Options.TargetType {
+	case C.ConvertorTypeRuleSetSource, C.ConvertorTypeRuleSetBinary:
+		var variants []string
+		if variant := downgradeVariant(options.Metadata); variant != "" {
+			variants = append(variants, variant)
+		}
+		if variant := systemVariant(options); variant != "" {
+			variants = append(variants, variant)
+		}
+		return strings.Join(variants, "+")
+	case C.ConvertorTypeClashRuleProvider:
+		return clash.Variant(options)
+	default:
+		return ""
+	}
 }
@@ -0,0 +1,182 @@
+package convertor
+
+import (
+	boxConstant "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/srsc/adapter"
+	"github.com/sagernet/srsc/common/semver"
+	C "github.com/sagernet/srsc/constant"
+)
+
+type headlessField struct {
+	name string
+	has  func(rule option.DefaultHeadlessRule) bool
+}
+
+type capability struct {
+	version        semver.Version
+	ruleSetVersion uint8
+	fields         []headlessField
+}
+
+// capabilities lists rule-set versions and headless rule items introduced by each sing-box version,
+// ordered by version, the last one must match the current rule-set version.
+var capabilities = []capability{
+	{
+		version:        semver.ParseVersion("1.8.0"),
+		ruleSetVersion: boxConstant.RuleSetVersion1,
+	},
+	{
+		version:        semver.ParseVersion("1.10.0"),
+		ruleSetVersion: boxConstant.RuleSetVersion2,
+		fields: []headlessField{
+			{"process_path_regex", func(rule option.DefaultHeadlessRule) bool {
+				return len(rule.ProcessPathRegex) > 0
+			}},
+			{"AdGuard domain", func(rule option.DefaultHeadlessRule) bool {
+				return len(rule.AdGuardDomain) > 0
+			}},
+		},
+	},
+	{
+		version:        semver.ParseVersion("1.11.0"),
+		ruleSetVersion: boxConstant.RuleSetVersion3,
+		fields: []headlessField{
+			{"network_type", func(rule option.DefaultHeadlessRule) bool {
+				return len(rule.NetworkType) > 0
+			}},
+			{"network_is_expensive", func(rule option.DefaultHeadlessRule) bool {
+				return rule.NetworkIsExpensive
+			}},
+			{"network_is_constrained", func(rule option.DefaultHeadlessRule) bool {
+				return rule.NetworkIsConstrained
+			}},
+		},
+	},
+	{
+		version:        semver.ParseVersion("1.13.0"),
+		ruleSetVersion: boxConstant.RuleSetVersion4,
+		fields: []headlessField{
+			{"network_interface_address", func(rule option.DefaultHeadlessRule) bool {
+				return rule.NetworkInterfaceAddress != nil && rule.NetworkInterfaceAddress.Size() > 0
+			}},
+			{"default_interface_address", func(rule option.DefaultHeadlessRule) bool {
+				return len(rule.DefaultInterfaceAddress) > 0
+			}},
+		},
+	},
+}
+
+func capabilityIndex(version semver.Version) int {
+	var index int
+	for i, it := range capabilities {
+		if version.GreaterThanOrEqual(it.version) {
+			index = i
+		}
+	}
+	return index
+}
+
+// downgradeVariant returns the name of the variant of rule-sets downgraded for metadata,
+// or empty if rule-sets are not downgraded.
+func downgradeVariant(metadata C.Metadata) string {
+	if metadata.Platform != C.PlatformSingBox || metadata.Version == nil {
+		return ""
+	}
+	index := capabilityIndex(*metadata.Version)
+	if index == len(capabilities)-1 {
+		return ""
+	}
+	return "sing-box-" + capabilities[index].version.String()
+}
+
+type unsupportedField struct {
+	headlessField
+	version semver.Version
+}
+
+type droppedRule struct {
+	rule   option.HeadlessRule
+	reason string
+}
+
+// Downgrade rewrites source to the rule-set version supported by sing-box of version.
+//
+// Rules with unsupported items are dropped, and so are unsupported sub-rules of logical rules
+// if the logical rule only matches less without them.
+func Downgrade(source *option.PlainRuleSetCompat, version *semver.Version, options adapter.ConvertOptions) error {
+	index := capabilityIndex(*version)
+	if index == len(capabilities)-1 {
+		return nil
+	}
+	source.Version = capabilities[index].ruleSetVersion
+	var unsupportedFields []unsupportedField
+	for _, it := range capabilities[index+1:] {
+		for _, field := range it.fields {
+			unsupportedFields = append(unsupportedFields, unsupportedField{field, it.version})
+		}
+	}
+	var rules []option.HeadlessRule
+	for _, rule := range source.Options.Rules {
+		downgradedRule, droppedRules, reason := downgradeRule(rule, unsupportedFields, false)
+		if reason != "" {
+			err := options.DropRule(adapter.RuleFrom(rule), reason)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		for _, dropped := range droppedRules {
+			err := options.DropRule(adapter.RuleFrom(dropped.rule), dropped.reason)
+			if err != nil {
+				return err
+			}
+		}
+		rules = append(rules, downgradedRule)
+	}
+	source.Options.Rules = rules
+	return nil
+}
+
+// downgradeRule returns the rule without unsupported sub-rules and the dropped sub-rules,
+// or the reason if the rule is unsupported.
+func downgradeRule(rule option.HeadlessRule, unsupportedFields []unsupportedField, inverted bool) (option.HeadlessRule, []droppedRule, string) {
+	switch rule.Type {
+	case boxConstant.RuleTypeDefault:
+		for _, field := range unsupportedFields {
+			if field.has(rule.DefaultOptions) {
+				return rule, nil, "`" + field.name + "` unsupported by sing-box < " + field.version.String()
+			}
+		}
+		return rule, nil, ""
+	case boxConstant.RuleTypeLogical:
+		inverted = inverted != rule.LogicalOptions.Invert
+		// without a sub-rule, `or` rules match less and `and` rules match more
+		droppable := (rule.LogicalOptions.Mode == boxConstant.LogicalTypeOr) != inverted
+		var (
+			rules        []option.HeadlessRule
+			droppedRules []droppedRule
+			dropReason   string
+		)
+		for _, subRule := range rule.LogicalOptions.Rules {
+			downgradedRule, subDroppedRules, reason := downgradeRule(subRule, unsupportedFields, inverted)
+			if reason != "" {
+				if !droppable {
+					return rule, nil, reason
+				}
+				droppedRules = append(droppedRules, droppedRule{subRule, reason})
+				dropReason = reason
+				continue
+			}
+			rules = append(rules, downgradedRule)
+			droppedRules = append(droppedRules, subDroppedRules...)
+		}
+		if len(rules) == 0 {
+			return rule, nil, dropReason
+		}
+		rule.LogicalOptions.Rules = rules
+		return rule, droppedRules, ""
+	default:
+		return rule, nil, ""
+	}
+}
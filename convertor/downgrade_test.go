@@ -0,0 +1,184 @@
+package convertor
+
+import (
+	"testing"
+
+	boxConstant "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/srsc/adapter"
+	"github.com/sagernet/srsc/common/semver"
+	C "github.com/sagernet/srsc/constant"
+	srscOption "github.com/sagernet/srsc/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func headlessRule(rule option.DefaultHeadlessRule) option.HeadlessRule {
+	return option.HeadlessRule{
+		Type:           boxConstant.RuleTypeDefault,
+		DefaultOptions: rule,
+	}
+}
+
+func logicalHeadlessRule(mode string, invert bool, rules ...option.HeadlessRule) option.HeadlessRule {
+	return option.HeadlessRule{
+		Type: boxConstant.RuleTypeLogical,
+		LogicalOptions: option.LogicalHeadlessRule{
+			Mode:   mode,
+			Rules:  rules,
+			Invert: invert,
+		},
+	}
+}
+
+func diagnosticReasons(diagnostics *adapter.Diagnostics) []string {
+	var reasons []string
+	for _, entry := range diagnostics.Entries() {
+		reasons = append(reasons, entry.Reason)
+	}
+	return reasons
+}
+
+func TestDowngrade(t *testing.T) {
+	t.Parallel()
+	domainRule := headlessRule(option.DefaultHeadlessRule{Domain: []string{"example.com"}})
+	processPathRegexRule := headlessRule(option.DefaultHeadlessRule{ProcessPathRegex: []string{"^/usr/bin/"}})
+	networkIsExpensiveRule := headlessRule(option.DefaultHeadlessRule{NetworkIsExpensive: true})
+	processPathRegexReason := "`process_path_regex` unsupported by sing-box < 1.10.0"
+	networkIsExpensiveReason := "`network_is_expensive` unsupported by sing-box < 1.11.0"
+	for _, testCase := range []struct {
+		name     string
+		version  string
+		rules    []option.HeadlessRule
+		expected []option.HeadlessRule
+		reasons  []string
+		ruleSet  uint8
+	}{
+		{
+			name:     "latest",
+			version:  "1.13.0",
+			rules:    []option.HeadlessRule{domainRule, processPathRegexRule, networkIsExpensiveRule},
+			expected: []option.HeadlessRule{domainRule, processPathRegexRule, networkIsExpensiveRule},
+			ruleSet:  boxConstant.RuleSetVersion4,
+		},
+		{
+			name:     "newer",
+			version:  "1.14.0-alpha.1",
+			rules:    []option.HeadlessRule{networkIsExpensiveRule},
+			expected: []option.HeadlessRule{networkIsExpensiveRule},
+			ruleSet:  boxConstant.RuleSetVersion4,
+		},
+		{
+			name:     "1.10",
+			version:  "1.10.7",
+			rules:    []option.HeadlessRule{domainRule, processPathRegexRule, networkIsExpensiveRule},
+			expected: []option.HeadlessRule{domainRule, processPathRegexRule},
+			reasons:  []string{networkIsExpensiveReason},
+			ruleSet:  boxConstant.RuleSetVersion2,
+		},
+		{
+			name:     "1.8",
+			version:  "1.9.0",
+			rules:    []option.HeadlessRule{domainRule, processPathRegexRule, networkIsExpensiveRule},
+			expected: []option.HeadlessRule{domainRule},
+			reasons:  []string{processPathRegexReason, networkIsExpensiveReason},
+			ruleSet:  boxConstant.RuleSetVersion1,
+		},
+		{
+			name:     "or drops sub-rule",
+			version:  "1.9.0",
+			rules:    []option.HeadlessRule{logicalHeadlessRule(boxConstant.LogicalTypeOr, false, domainRule, processPathRegexRule)},
+			expected: []option.HeadlessRule{logicalHeadlessRule(boxConstant.LogicalTypeOr, false, domainRule)},
+			reasons:  []string{processPathRegexReason},
+			ruleSet:  boxConstant.RuleSetVersion1,
+		},
+		{
+			name:    "or without sub-rules",
+			version: "1.9.0",
+			rules:   []option.HeadlessRule{logicalHeadlessRule(boxConstant.LogicalTypeOr, false, processPathRegexRule, networkIsExpensiveRule)},
+			reasons: []string{networkIsExpensiveReason},
+			ruleSet: boxConstant.RuleSetVersion1,
+		},
+		{
+			name:    "and drops rule",
+			version: "1.9.0",
+			rules:   []option.HeadlessRule{logicalHeadlessRule(boxConstant.LogicalTypeAnd, false, domainRule, processPathRegexRule)},
+			reasons: []string{processPathRegexReason},
+			ruleSet: boxConstant.RuleSetVersion1,
+		},
+		{
+			name:    "inverted or drops rule",
+			version: "1.9.0",
+			rules:   []option.HeadlessRule{logicalHeadlessRule(boxConstant.LogicalTypeOr, true, domainRule, processPathRegexRule)},
+			reasons: []string{processPathRegexReason},
+			ruleSet: boxConstant.RuleSetVersion1,
+		},
+		{
+			name:     "inverted and drops sub-rule",
+			version:  "1.9.0",
+			rules:    []option.HeadlessRule{logicalHeadlessRule(boxConstant.LogicalTypeAnd, true, domainRule, processPathRegexRule)},
+			expected: []option.HeadlessRule{logicalHeadlessRule(boxConstant.LogicalTypeAnd, true, domainRule)},
+			reasons:  []string{processPathRegexReason},
+			ruleSet:  boxConstant.RuleSetVersion1,
+		},
+		{
+			name:    "nested inverted or dropped entirely",
+			version: "1.9.0",
+			rules: []option.HeadlessRule{logicalHeadlessRule(boxConstant.LogicalTypeAnd, true,
+				domainRule,
+				logicalHeadlessRule(boxConstant.LogicalTypeOr, false, domainRule, processPathRegexRule),
+			)},
+			expected: []option.HeadlessRule{logicalHeadlessRule(boxConstant.LogicalTypeAnd, true, domainRule)},
+			reasons:  []string{processPathRegexReason},
+			ruleSet:  boxConstant.RuleSetVersion1,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			version := semver.ParseVersion(testCase.version)
+			source := &option.PlainRuleSetCompat{
+				Version: boxConstant.RuleSetVersionCurrent,
+				Options: option.PlainRuleSet{Rules: testCase.rules},
+			}
+			diagnostics := adapter.NewDiagnostics()
+			err := Downgrade(source, &version, adapter.ConvertOptions{Diagnostics: diagnostics})
+			require.NoError(t, err)
+			require.Equal(t, testCase.ruleSet, source.Version)
+			require.Equal(t, testCase.expected, source.Options.Rules)
+			require.Equal(t, testCase.reasons, diagnosticReasons(diagnostics))
+		})
+	}
+}
+
+func TestDowngradeStrict(t *testing.T) {
+	t.Parallel()
+	version := semver.ParseVersion("1.9.0")
+	source := &option.PlainRuleSetCompat{
+		Version: boxConstant.RuleSetVersionCurrent,
+		Options: option.PlainRuleSet{Rules: []option.HeadlessRule{
+			headlessRule(option.DefaultHeadlessRule{ProcessPathRegex: []string{"^/usr/bin/"}}),
+		}},
+	}
+	err := Downgrade(source, &version, adapter.ConvertOptions{Options: srscOption.ConvertOptions{TargetConvertOptions: srscOption.TargetConvertOptions{Strict: true}}})
+	require.ErrorContains(t, err, "strict mode")
+}
+
+func TestDowngradeVariant(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		userAgent string
+		variant   string
+	}{
+		{"SFA/1.13.0 (1; sing-box 1.13.0)", ""},
+		{"SFA/1.12.4 (1; sing-box 1.12.4)", "sing-box-1.11.0"},
+		{"SFI/1.10.0 (1; sing-box 1.10.0)", "sing-box-1.10.0"},
+		{"sing-box 1.7.0", "sing-box-1.8.0"},
+		{"mihomo/1.18.0", ""},
+		{"curl/8.4.0", ""},
+	} {
+		t.Run(testCase.userAgent, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, testCase.variant, downgradeVariant(C.DetectMetadata(testCase.userAgent)))
+		})
+	}
+}
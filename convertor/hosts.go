@@ -0,0 +1,133 @@
+package convertor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net/netip"
+	"strings"
+
+	boxConstant "github.com/sagernet/sing-box/constant"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/srsc/adapter"
+	C "github.com/sagernet/srsc/constant"
+
+	"golang.org/x/net/idna"
+)
+
+var _ adapter.Convertor = (*HostsFile)(nil)
+
+// localHostnames are mapped to local addresses by hosts files themselves rather than blocked.
+var localHostnames = map[string]bool{
+	"localhost":             true,
+	"localhost.localdomain": true,
+	"local":                 true,
+	"broadcasthost":         true,
+	"ip6-localhost":         true,
+	"ip6-loopback":          true,
+	"ip6-localnet":          true,
+	"ip6-mcastprefix":       true,
+	"ip6-allnodes":          true,
+	"ip6-allrouters":        true,
+	"ip6-allhosts":          true,
+}
+
+type HostsFile struct{}
+
+func (h *HostsFile) Type() string {
+	return C.ConvertorTypeHostsFile
+}
+
+func (h *HostsFile) ContentType(options adapter.ConvertOptions) string {
+	return "text/plain"
+}
+
+func (h *HostsFile) From(ctx context.Context, content []byte, options adapter.ConvertOptions) ([]adapter.Rule, error) {
+	var rule adapter.DefaultRule
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(nil, len(content)+1)
+	for scanner.Scan() {
+		ruleLine := strings.TrimSpace(scanner.Text())
+		hostLine, _, _ := strings.Cut(ruleLine, "#")
+		fields := strings.Fields(hostLine)
+		if len(fields) == 0 {
+			continue
+		}
+		address, err := netip.ParseAddr(fields[0])
+		if err != nil {
+			err = options.DropLine(ruleLine, "invalid address: ", fields[0])
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		var hostnames []string
+		for _, hostname := range fields[1:] {
+			if !localHostnames[strings.ToLower(hostname)] {
+				hostnames = append(hostnames, hostname)
+			}
+		}
+		if len(hostnames) == 0 {
+			if len(fields) == 1 {
+				err = options.DropLine(ruleLine, "missing hostname")
+				if err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		if !address.IsUnspecified() && !address.IsLoopback() {
+			err = options.DropLine(ruleLine, "not a sink address: ", address)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		for _, hostname := range hostnames {
+			domain, err := idna.Lookup.ToASCII(strings.TrimSuffix(strings.ToLower(hostname), "."))
+			if err != nil || M.ParseAddr(domain).IsValid() || !M.IsDomainName(domain) {
+				err = options.DropLine(ruleLine, "invalid domain name: ", hostname)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			rule.Domain = append(rule.Domain, domain)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, E.Cause(err, "read hosts file")
+	}
+	if len(rule.Domain) == 0 {
+		return nil, E.New("no domain found in hosts file")
+	}
+	return []adapter.Rule{{Type: boxConstant.RuleTypeDefault, DefaultOptions: rule}}, nil
+}
+
+func (h *HostsFile) To(ctx context.Context, contentRules []adapter.Rule, options adapter.ConvertOptions) ([]byte, error) {
+	sinkAddress := options.Options.HostsOptions.SinkAddress.Build(netip.IPv4Unspecified()).String()
+	var output bytes.Buffer
+	for _, rule := range contentRules {
+		if rule.Type != boxConstant.RuleTypeDefault || !adapter.IsDestinationAddressRule(rule.DefaultOptions) {
+			err := options.DropRule(rule, "unsupported by hosts file")
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		droppedRule := rule.DefaultOptions
+		droppedRule.Domain = nil
+		if len(droppedRule.DomainSuffix) > 0 || len(droppedRule.DomainKeyword) > 0 || len(droppedRule.DomainRegex) > 0 ||
+			len(droppedRule.IPCIDR) > 0 || len(droppedRule.GEOIP) > 0 || len(droppedRule.IPASN) > 0 {
+			err := options.DropRule(adapter.Rule{Type: boxConstant.RuleTypeDefault, DefaultOptions: droppedRule}, "items unsupported by hosts file")
+			if err != nil {
+				return nil, err
+			}
+		}
+		for _, domain := range rule.DefaultOptions.Domain {
+			output.WriteString(sinkAddress + " " + domain + "\n")
+		}
+	}
+	return output.Bytes(), nil
+}
@@ -0,0 +1,144 @@
+package convertor
+
+import (
+	"context"
+	"net/netip"
+	"strings"
+	"testing"
+
+	boxConstant "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common/json/badoption"
+	"github.com/sagernet/srsc/adapter"
+	srscOption "github.com/sagernet/srsc/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostsFrom(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name     string
+		content  string
+		domains  []string
+		dropped  []string
+		errorMsg string
+	}{
+		{
+			name:    "sink addresses",
+			content: "0.0.0.0 a.com b.com\n127.0.0.1 c.com\n::  d.com\n::1 e.com\n",
+			domains: []string{"a.com", "b.com", "c.com", "d.com", "e.com"},
+		},
+		{
+			name:    "comments",
+			content: "# comment\n\n0.0.0.0 a.com # b.com\n  0.0.0.0\tc.com\t#\n",
+			domains: []string{"a.com", "c.com"},
+		},
+		{
+			name:    "local hostnames",
+			content: "127.0.0.1 localhost\n::1 localhost ip6-localhost ip6-loopback\n255.255.255.255 broadcasthost\n0.0.0.0 a.com\n",
+			domains: []string{"a.com"},
+		},
+		{
+			name:    "normalized",
+			content: "0.0.0.0 Ads.Example.COM\n0.0.0.0 tracker.example.com.\n0.0.0.0 bücher.example\n",
+			domains: []string{"ads.example.com", "tracker.example.com", "xn--bcher-kva.example"},
+		},
+		{
+			name:    "dropped lines",
+			content: "0.0.0.0 a.com\n192.168.1.1 router.lan\nexample.com\n0.0.0.0\n0.0.0.0 1.2.3.4 bad_name..com\n",
+			domains: []string{"a.com"},
+			dropped: []string{
+				"not a sink address: 192.168.1.1",
+				"invalid address: example.com",
+				"missing hostname",
+				"invalid domain name: 1.2.3.4",
+				"invalid domain name: bad_name..com",
+			},
+		},
+		{
+			name:    "long line",
+			content: "0.0.0.0 a.com # " + strings.Repeat("x", 128*1024) + "\n0.0.0.0 b.com\n",
+			domains: []string{"a.com", "b.com"},
+		},
+		{
+			name:     "no domain",
+			content:  "# empty\n127.0.0.1 localhost\n",
+			errorMsg: "no domain found in hosts file",
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			diagnostics := adapter.NewDiagnostics()
+			rules, err := (&HostsFile{}).From(context.Background(), []byte(testCase.content), adapter.ConvertOptions{Diagnostics: diagnostics})
+			if testCase.errorMsg != "" {
+				require.EqualError(t, err, testCase.errorMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, rules, 1)
+			require.Equal(t, boxConstant.RuleTypeDefault, rules[0].Type)
+			require.Equal(t, testCase.domains, []string(rules[0].DefaultOptions.Domain))
+			require.Equal(t, testCase.dropped, diagnosticReasons(diagnostics))
+		})
+	}
+}
+
+func TestHostsTo(t *testing.T) {
+	t.Parallel()
+	sinkAddress := badoption.Addr(netip.MustParseAddr("::"))
+	domainRule := adapter.Rule{Type: boxConstant.RuleTypeDefault}
+	domainRule.DefaultOptions.Domain = []string{"a.com", "b.com"}
+	mixedRule := adapter.Rule{Type: boxConstant.RuleTypeDefault}
+	mixedRule.DefaultOptions.Domain = []string{"c.com"}
+	mixedRule.DefaultOptions.DomainSuffix = []string{"d.com"}
+	portRule := adapter.Rule{Type: boxConstant.RuleTypeDefault}
+	portRule.DefaultOptions.Domain = []string{"e.com"}
+	portRule.DefaultOptions.Port = []uint16{443}
+	logicalRule := adapter.Rule{Type: boxConstant.RuleTypeLogical}
+	logicalRule.LogicalOptions.Mode = boxConstant.LogicalTypeOr
+	logicalRule.LogicalOptions.Rules = []adapter.Rule{domainRule}
+	for _, testCase := range []struct {
+		name     string
+		rules    []adapter.Rule
+		options  srscOption.HostsFileTargetOptions
+		expected string
+		dropped  []string
+	}{
+		{
+			name:     "default sink address",
+			rules:    []adapter.Rule{domainRule},
+			expected: "0.0.0.0 a.com\n0.0.0.0 b.com\n",
+		},
+		{
+			name:     "sink address",
+			rules:    []adapter.Rule{domainRule},
+			options:  srscOption.HostsFileTargetOptions{SinkAddress: &sinkAddress},
+			expected: ":: a.com\n:: b.com\n",
+		},
+		{
+			name:     "unsupported items",
+			rules:    []adapter.Rule{mixedRule},
+			expected: "0.0.0.0 c.com\n",
+			dropped:  []string{"items unsupported by hosts file"},
+		},
+		{
+			name:    "unsupported rules",
+			rules:   []adapter.Rule{portRule, logicalRule},
+			dropped: []string{"unsupported by hosts file", "unsupported by hosts file"},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			diagnostics := adapter.NewDiagnostics()
+			content, err := (&HostsFile{}).To(context.Background(), testCase.rules, adapter.ConvertOptions{
+				Options: srscOption.ConvertOptions{
+					TargetConvertOptions: srscOption.TargetConvertOptions{HostsOptions: testCase.options},
+				},
+				Diagnostics: diagnostics,
+			})
+			require.NoError(t, err)
+			require.Equal(t, testCase.expected, string(content))
+			require.Equal(t, testCase.dropped, diagnosticReasons(diagnostics))
+		})
+	}
+}
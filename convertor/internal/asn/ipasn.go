@@ -6,7 +6,9 @@ import (
 	"github.com/sagernet/sing-box/constant"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/json/badoption"
+	"github.com/sagernet/sing/service"
 	"github.com/sagernet/srsc/adapter"
+	"github.com/sagernet/srsc/metrics"
 )
 
 func ConvertIPASNToIPCIDR(ctx context.Context, rules []adapter.Rule) ([]adapter.Rule, error) {
@@ -15,6 +17,7 @@ func ConvertIPASNToIPCIDR(ctx context.Context, rules []adapter.Rule) ([]adapter.
 	}
 
 	resolver := NewASNResolver()
+	resolver.metrics = service.FromContext[*metrics.Registry](ctx)
 	if err := walkRules(rules, func(rule *adapter.Rule) error {
 		if rule.Type != constant.RuleTypeDefault {
 			return nil
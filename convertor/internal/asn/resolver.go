@@ -15,6 +15,7 @@ import (
 	E "github.com/sagernet/sing/common/exceptions"
 	F "github.com/sagernet/sing/common/format"
 	"github.com/sagernet/srsc/constant"
+	"github.com/sagernet/srsc/metrics"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -50,6 +51,7 @@ type ASNResolver struct {
 	cache     sync.Map
 	client    *http.Client
 	userAgent string
+	metrics   *metrics.Registry
 }
 
 func NewASNResolver() *ASNResolver {
@@ -163,20 +165,25 @@ func (r *ASNResolver) ResolveASN(ctx context.Context, asn string) ([]string, err
 		return nil, err
 	}
 
+	startAt := time.Now()
 	if prefixes, ok := r.loadFromCache(asnID); ok {
+		r.metrics.ObserveASNLookup("cached", time.Since(startAt))
 		return prefixes, nil
 	}
 
 	if prefixes, err := r.fetchFromBGPView(ctx, asnID); err == nil && len(prefixes) > 0 {
 		r.cache.Store(asnID, slices.Clone(prefixes))
+		r.metrics.ObserveASNLookup("bgpview", time.Since(startAt))
 		return prefixes, nil
 	}
 
 	if prefixes, err := r.fetchFromRIPE(ctx, asnID); err == nil && len(prefixes) > 0 {
 		r.cache.Store(asnID, slices.Clone(prefixes))
+		r.metrics.ObserveASNLookup("ripe", time.Since(startAt))
 		return prefixes, nil
 	}
 
+	r.metrics.ObserveASNLookup("empty", time.Since(startAt))
 	empty := make([]string, 0)
 	r.cache.Store(asnID, empty)
 	return empty, nil
@@ -0,0 +1,41 @@
+package convertor
+
+import (
+	"context"
+
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/srsc/adapter"
+	C "github.com/sagernet/srsc/constant"
+	"github.com/sagernet/srsc/convertor/internal/asn"
+)
+
+// OptimizeRules applies optimizations enabled in target options to rules before encoding.
+//
+// Resources are embedded (and IPASN is resolved for sing-box) first, so that the expanded items are also optimized.
+func OptimizeRules(ctx context.Context, logger logger.Logger, rules []adapter.Rule, options adapter.ConvertOptions) ([]adapter.Rule, error) {
+	if !options.Options.OptimizeDomain && !options.Options.AggregateIPCIDR {
+		return rules, nil
+	}
+	rules, err := adapter.EmbedResourceRules(ctx, rules)
+	if err != nil {
+		return nil, err
+	}
+	if options.Options.OptimizeDomain {
+		var removed int
+		rules, removed = adapter.OptimizeDomain(rules)
+		logger.Debug("optimize domain: removed ", removed, " items")
+	}
+	if options.Options.AggregateIPCIDR {
+		if options.Metadata.Platform == C.PlatformSingBox {
+			rules, err = asn.ConvertIPASNToIPCIDR(ctx, rules)
+			if err != nil {
+				return nil, E.Cause(err, "convert IP-ASN to IP-CIDR")
+			}
+		}
+		var removed int
+		rules, removed = adapter.AggregateIPCIDR(rules)
+		logger.Debug("aggregate IP CIDR: removed ", removed, " items")
+	}
+	return rules, nil
+}
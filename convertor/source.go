@@ -11,7 +11,6 @@ import (
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/json"
 	"github.com/sagernet/srsc/adapter"
-	"github.com/sagernet/srsc/common/semver"
 	C "github.com/sagernet/srsc/constant"
 	"github.com/sagernet/srsc/convertor/internal/asn"
 )
@@ -52,16 +51,27 @@ func (s *RuleSetSource) To(ctx context.Context, contentRules []adapter.Rule, opt
 		}
 	}
 
+	rules, err := headlessRules(convertedRules, options)
+	if err != nil {
+		return nil, err
+	}
 	ruleSet := &option.PlainRuleSetCompat{
 		Version: boxConstant.RuleSetVersionCurrent,
 		Options: option.PlainRuleSet{
-			Rules: common.Map(common.Filter(convertedRules, func(it adapter.Rule) bool {
-				return it.Headlessable()
-			}), adapter.Rule.ToHeadless),
+			Rules: rules,
 		},
 	}
+	if options.Metadata.Platform == C.PlatformSingBox && options.Options.FilterSystemItems && options.Metadata.System != C.SystemUnknown {
+		err = FilterSystem(ruleSet, options.Metadata.System, options)
+		if err != nil {
+			return nil, err
+		}
+	}
 	if options.Metadata.Platform == C.PlatformSingBox && options.Metadata.Version != nil {
-		Downgrade(ruleSet, options.Metadata.Version)
+		err = Downgrade(ruleSet, options.Metadata.Version, options)
+		if err != nil {
+			return nil, err
+		}
 	}
 	buffer := new(bytes.Buffer)
 	encoder := json.NewEncoder(buffer)
@@ -73,34 +83,18 @@ func (s *RuleSetSource) To(ctx context.Context, contentRules []adapter.Rule, opt
 	return buffer.Bytes(), nil
 }
 
-func Downgrade(source *option.PlainRuleSetCompat, version *semver.Version) {
-	if version.LessThan(semver.ParseVersion("1.11.0")) {
-		source.Version = boxConstant.RuleSetVersion2
-		source.Options.Rules = common.Filter(source.Options.Rules, filter1100Rule)
-	}
-	if version.LessThan(semver.ParseVersion("1.10.0")) {
-		source.Version = boxConstant.RuleSetVersion1
-	}
-}
-
-func filter1100Rule(it option.HeadlessRule) bool {
-	return !hasRule([]option.HeadlessRule{it}, func(it option.DefaultHeadlessRule) bool {
-		return len(it.NetworkType) > 0 || it.NetworkIsExpensive || it.NetworkIsConstrained
-	})
-}
-
-func hasRule(rules []option.HeadlessRule, cond func(rule option.DefaultHeadlessRule) bool) bool {
+// headlessRules converts rules to sing-box headless rules, rules that are not headlessable are recorded as dropped.
+func headlessRules(rules []adapter.Rule, options adapter.ConvertOptions) ([]option.HeadlessRule, error) {
+	var headlessRules []option.HeadlessRule
 	for _, rule := range rules {
-		switch rule.Type {
-		case boxConstant.RuleTypeDefault:
-			if cond(rule.DefaultOptions) {
-				return true
-			}
-		case boxConstant.RuleTypeLogical:
-			if hasRule(rule.LogicalOptions.Rules, cond) {
-				return true
+		if !rule.Headlessable() {
+			err := options.DropRule(rule, "unsupported by sing-box rule-set")
+			if err != nil {
+				return nil, err
 			}
+			continue
 		}
+		headlessRules = append(headlessRules, rule.ToHeadless())
 	}
-	return false
+	return headlessRules, nil
 }
@@ -32,10 +32,19 @@ func (s *SurgeRuleSet) From(ctx context.Context, content []byte, options adapter
 		var rules []adapter.Rule
 		scanner := bufio.NewScanner(bytes.NewReader(content))
 		for scanner.Scan() {
-			rule, _ := clash.FromSurgeLine(scanner.Text())
-			if rule != nil {
-				rules = append(rules, *rule)
+			ruleLine := strings.TrimSpace(scanner.Text())
+			if ruleLine == "" || strings.HasPrefix(ruleLine, "#") || strings.HasPrefix(ruleLine, "//") {
+				continue
+			}
+			rule, err := clash.FromSurgeLine(ruleLine)
+			if err != nil {
+				err = options.DropLine(ruleLine, err)
+				if err != nil {
+					return nil, err
+				}
+				continue
 			}
+			rules = append(rules, *rule)
 		}
 		return adapter.MergeRules(rules), nil
 	case "domain":
@@ -70,17 +79,22 @@ func (s *SurgeRuleSet) To(ctx context.Context, contentRules []adapter.Rule, opti
 		for _, rule := range convertedRules {
 			ruleLines, err := clash.ToSurgeLines(rule)
 			if err != nil {
+				err = options.DropRule(rule, err)
+				if err != nil {
+					return nil, err
+				}
 				continue
 			}
 			lines = append(lines, ruleLines...)
 		}
 		return []byte(strings.Join(lines, "\n")), nil
 	case "domain":
+		behaviorRules, err := clash.FilterBehaviorRules(behavior, contentRules, options)
+		if err != nil {
+			return nil, err
+		}
 		var output bytes.Buffer
-		for _, rule := range contentRules {
-			if rule.Type != boxConstant.RuleTypeDefault || !adapter.IsDestinationAddressRule(rule.DefaultOptions) {
-				continue
-			}
+		for _, rule := range behaviorRules {
 			for _, domain := range rule.DefaultOptions.Domain {
 				output.WriteString(domain + "\n")
 			}
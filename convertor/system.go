@@ -0,0 +1,129 @@
+package convertor
+
+import (
+	boxConstant "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/srsc/adapter"
+	C "github.com/sagernet/srsc/constant"
+)
+
+type systemField struct {
+	headlessField
+	systems []C.System
+}
+
+// systemFields lists headless rule items only supported on some systems of sing-box graphical clients,
+// following platform notes of route rule items in the sing-box documentation.
+//
+// The graphical client on Android resolves connection owners to package names only,
+// and tvOS provides no Wi-Fi information.
+var systemFields = []systemField{
+	{headlessField{"process_name", func(rule option.DefaultHeadlessRule) bool {
+		return len(rule.ProcessName) > 0
+	}}, []C.System{C.SystemMacOS}},
+	{headlessField{"process_path", func(rule option.DefaultHeadlessRule) bool {
+		return len(rule.ProcessPath) > 0
+	}}, []C.System{C.SystemMacOS}},
+	{headlessField{"process_path_regex", func(rule option.DefaultHeadlessRule) bool {
+		return len(rule.ProcessPathRegex) > 0
+	}}, []C.System{C.SystemMacOS}},
+	{headlessField{"package_name", func(rule option.DefaultHeadlessRule) bool {
+		return len(rule.PackageName) > 0
+	}}, []C.System{C.SystemAndroid}},
+	{headlessField{"network_is_constrained", func(rule option.DefaultHeadlessRule) bool {
+		return rule.NetworkIsConstrained
+	}}, []C.System{C.SystemiOS, C.SystemMacOS, C.SystemAppleTVOS}},
+	{headlessField{"wifi_ssid", func(rule option.DefaultHeadlessRule) bool {
+		return len(rule.WIFISSID) > 0
+	}}, []C.System{C.SystemAndroid, C.SystemiOS, C.SystemMacOS}},
+	{headlessField{"wifi_bssid", func(rule option.DefaultHeadlessRule) bool {
+		return len(rule.WIFIBSSID) > 0
+	}}, []C.System{C.SystemAndroid, C.SystemiOS, C.SystemMacOS}},
+	{headlessField{"default_interface_address", func(rule option.DefaultHeadlessRule) bool {
+		return len(rule.DefaultInterfaceAddress) > 0
+	}}, []C.System{C.SystemMacOS}},
+}
+
+func systemVariant(options adapter.ConvertOptions) string {
+	if !options.Options.FilterSystemItems || options.Metadata.Platform != C.PlatformSingBox {
+		return ""
+	}
+	return string(options.Metadata.System)
+}
+
+// FilterSystem removes rules never matching on system since they contain items unsupported on system.
+//
+// Sub-rules of logical rules are removed if the logical rule matches the same without them,
+// inverted rules always matching on system are kept unchanged.
+func FilterSystem(source *option.PlainRuleSetCompat, system C.System, options adapter.ConvertOptions) error {
+	var rules []option.HeadlessRule
+	for _, rule := range source.Options.Rules {
+		filteredRule, droppedRules, reason := filterSystemRule(rule, system)
+		if reason != "" {
+			err := options.DropRule(adapter.RuleFrom(rule), reason)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		for _, dropped := range droppedRules {
+			err := options.DropRule(adapter.RuleFrom(dropped.rule), dropped.reason)
+			if err != nil {
+				return err
+			}
+		}
+		rules = append(rules, filteredRule)
+	}
+	source.Options.Rules = rules
+	return nil
+}
+
+// filterSystemRule returns the rule without sub-rules never matching on system and the removed sub-rules,
+// or the reason if the rule never matches on system.
+func filterSystemRule(rule option.HeadlessRule, system C.System) (option.HeadlessRule, []droppedRule, string) {
+	switch rule.Type {
+	case boxConstant.RuleTypeDefault:
+		if rule.DefaultOptions.Invert {
+			return rule, nil, ""
+		}
+		for _, field := range systemFields {
+			if field.has(rule.DefaultOptions) && !common.Contains(field.systems, system) {
+				return rule, nil, "`" + field.name + "` unsupported on " + string(system)
+			}
+		}
+		return rule, nil, ""
+	case boxConstant.RuleTypeLogical:
+		var (
+			rules        []option.HeadlessRule
+			droppedRules []droppedRule
+			dropReason   string
+		)
+		for _, subRule := range rule.LogicalOptions.Rules {
+			filteredRule, subDroppedRules, reason := filterSystemRule(subRule, system)
+			if reason != "" {
+				if rule.LogicalOptions.Mode == boxConstant.LogicalTypeAnd {
+					if rule.LogicalOptions.Invert {
+						return rule, nil, ""
+					}
+					return rule, nil, reason
+				}
+				droppedRules = append(droppedRules, droppedRule{subRule, reason})
+				dropReason = reason
+				continue
+			}
+			rules = append(rules, filteredRule)
+			droppedRules = append(droppedRules, subDroppedRules...)
+		}
+		if len(rules) == 0 {
+			if rule.LogicalOptions.Invert {
+				return rule, nil, ""
+			}
+			return rule, nil, dropReason
+		}
+		rule.LogicalOptions.Rules = rules
+		return rule, droppedRules, ""
+	default:
+		return rule, nil, ""
+	}
+}
@@ -0,0 +1,138 @@
+package convertor
+
+import (
+	"testing"
+
+	boxConstant "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/srsc/adapter"
+	C "github.com/sagernet/srsc/constant"
+	srscOption "github.com/sagernet/srsc/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterSystem(t *testing.T) {
+	t.Parallel()
+	domainRule := headlessRule(option.DefaultHeadlessRule{Domain: []string{"example.com"}})
+	processNameRule := headlessRule(option.DefaultHeadlessRule{ProcessName: []string{"curl"}})
+	packageNameRule := headlessRule(option.DefaultHeadlessRule{PackageName: []string{"com.example"}})
+	wifiSSIDRule := headlessRule(option.DefaultHeadlessRule{WIFISSID: []string{"home"}})
+	invertedProcessNameRule := headlessRule(option.DefaultHeadlessRule{ProcessName: []string{"curl"}, Invert: true})
+	for _, testCase := range []struct {
+		name     string
+		system   C.System
+		rules    []option.HeadlessRule
+		expected []option.HeadlessRule
+		reasons  []string
+	}{
+		{
+			name:     "supported",
+			system:   C.SystemMacOS,
+			rules:    []option.HeadlessRule{domainRule, processNameRule, wifiSSIDRule},
+			expected: []option.HeadlessRule{domainRule, processNameRule, wifiSSIDRule},
+		},
+		{
+			name:     "unsupported",
+			system:   C.SystemiOS,
+			rules:    []option.HeadlessRule{domainRule, processNameRule, packageNameRule, wifiSSIDRule},
+			expected: []option.HeadlessRule{domainRule, wifiSSIDRule},
+			reasons:  []string{"`process_name` unsupported on ios", "`package_name` unsupported on ios"},
+		},
+		{
+			name:    "wifi on tvos",
+			system:  C.SystemAppleTVOS,
+			rules:   []option.HeadlessRule{wifiSSIDRule},
+			reasons: []string{"`wifi_ssid` unsupported on tvos"},
+		},
+		{
+			name:     "package name on android",
+			system:   C.SystemAndroid,
+			rules:    []option.HeadlessRule{packageNameRule, processNameRule},
+			expected: []option.HeadlessRule{packageNameRule},
+			reasons:  []string{"`process_name` unsupported on android"},
+		},
+		{
+			name:     "inverted rule kept",
+			system:   C.SystemiOS,
+			rules:    []option.HeadlessRule{invertedProcessNameRule},
+			expected: []option.HeadlessRule{invertedProcessNameRule},
+		},
+		{
+			name:    "and dropped",
+			system:  C.SystemiOS,
+			rules:   []option.HeadlessRule{logicalHeadlessRule(boxConstant.LogicalTypeAnd, false, domainRule, processNameRule)},
+			reasons: []string{"`process_name` unsupported on ios"},
+		},
+		{
+			name:     "inverted and kept",
+			system:   C.SystemiOS,
+			rules:    []option.HeadlessRule{logicalHeadlessRule(boxConstant.LogicalTypeAnd, true, domainRule, processNameRule)},
+			expected: []option.HeadlessRule{logicalHeadlessRule(boxConstant.LogicalTypeAnd, true, domainRule, processNameRule)},
+		},
+		{
+			name:     "or sub-rule removed",
+			system:   C.SystemiOS,
+			rules:    []option.HeadlessRule{logicalHeadlessRule(boxConstant.LogicalTypeOr, false, domainRule, processNameRule)},
+			expected: []option.HeadlessRule{logicalHeadlessRule(boxConstant.LogicalTypeOr, false, domainRule)},
+			reasons:  []string{"`process_name` unsupported on ios"},
+		},
+		{
+			name:     "inverted or sub-rule removed",
+			system:   C.SystemiOS,
+			rules:    []option.HeadlessRule{logicalHeadlessRule(boxConstant.LogicalTypeOr, true, domainRule, processNameRule)},
+			expected: []option.HeadlessRule{logicalHeadlessRule(boxConstant.LogicalTypeOr, true, domainRule)},
+			reasons:  []string{"`process_name` unsupported on ios"},
+		},
+		{
+			name:    "or without sub-rules",
+			system:  C.SystemiOS,
+			rules:   []option.HeadlessRule{logicalHeadlessRule(boxConstant.LogicalTypeOr, false, processNameRule, packageNameRule)},
+			reasons: []string{"`package_name` unsupported on ios"},
+		},
+		{
+			name:     "inverted or without sub-rules kept",
+			system:   C.SystemiOS,
+			rules:    []option.HeadlessRule{logicalHeadlessRule(boxConstant.LogicalTypeOr, true, processNameRule, packageNameRule)},
+			expected: []option.HeadlessRule{logicalHeadlessRule(boxConstant.LogicalTypeOr, true, processNameRule, packageNameRule)},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			source := &option.PlainRuleSetCompat{
+				Version: boxConstant.RuleSetVersionCurrent,
+				Options: option.PlainRuleSet{Rules: testCase.rules},
+			}
+			diagnostics := adapter.NewDiagnostics()
+			err := FilterSystem(source, testCase.system, adapter.ConvertOptions{Diagnostics: diagnostics})
+			require.NoError(t, err)
+			require.Equal(t, testCase.expected, source.Options.Rules)
+			require.Equal(t, testCase.reasons, diagnosticReasons(diagnostics))
+		})
+	}
+}
+
+func TestSystemVariant(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		userAgent string
+		filter    bool
+		variant   string
+	}{
+		{"SFI/1.13.0 (1; sing-box 1.13.0)", true, "ios"},
+		{"SFA/1.13.0 (1; sing-box 1.13.0)", true, "android"},
+		{"SFI/1.13.0 (1; sing-box 1.13.0)", false, ""},
+		{"sing-box 1.13.0", true, ""},
+		{"Surge iOS/2920", true, ""},
+	} {
+		t.Run(testCase.userAgent, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, testCase.variant, systemVariant(adapter.ConvertOptions{
+				Options: srscOption.ConvertOptions{
+					TargetConvertOptions: srscOption.TargetConvertOptions{FilterSystemItems: testCase.filter},
+				},
+				Metadata: C.DetectMetadata(testCase.userAgent),
+			}))
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package endpoint
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/sagernet/sing/common/auth"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/srsc/option"
+)
+
+type internalRequestKey struct{}
+
+// ContextWithInternalRequest marks requests with ctx as issued by srsc itself, such as builds, which skip authentication.
+func ContextWithInternalRequest(ctx context.Context) context.Context {
+	return context.WithValue(ctx, internalRequestKey{}, true)
+}
+
+func isInternalRequest(ctx context.Context) bool {
+	internal, _ := ctx.Value(internalRequestKey{}).(bool)
+	return internal
+}
+
+// Authenticator restricts access to endpoints by credentials and source address.
+//
+// Methods are safe to call on a nil Authenticator, all requests are accepted in that case.
+type Authenticator struct {
+	logger         logger.ContextLogger
+	tokens         []string
+	queryParameter string
+	users          *auth.Authenticator
+	allowedIPs     []netip.Prefix
+}
+
+func NewAuthenticator(logger logger.ContextLogger, options *option.AuthOptions) *Authenticator {
+	if options == nil {
+		return nil
+	}
+	if len(options.Tokens) == 0 && len(options.Users) == 0 && len(options.AllowedIPs) == 0 {
+		return nil
+	}
+	authenticator := &Authenticator{
+		logger:         logger,
+		tokens:         options.Tokens,
+		queryParameter: options.QueryParameter,
+		users:          auth.NewAuthenticator(options.Users),
+	}
+	for _, prefix := range options.AllowedIPs {
+		authenticator.allowedIPs = append(authenticator.allowedIPs, prefix.Build(netip.Prefix{}))
+	}
+	return authenticator
+}
+
+// Handler wraps handler to reject unauthorized requests with 401 and requests from disallowed addresses with 403.
+func (a *Authenticator) Handler(handler http.Handler) http.Handler {
+	if a == nil {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isInternalRequest(r.Context()) {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		if !a.allowAddress(r) {
+			a.logger.Warn("denied ", r.RemoteAddr, " - ", r.Header.Get("User-Agent"), " \"", r.Method, " ", r.URL.Path, " ", r.Proto, "\": address not allowed")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if !a.authenticate(r) {
+			a.logger.Warn("denied ", r.RemoteAddr, " - ", r.Header.Get("User-Agent"), " \"", r.Method, " ", r.URL.Path, " ", r.Proto, "\": unauthorized")
+			if a.users != nil {
+				w.Header().Set("WWW-Authenticate", "Basic realm=\"srsc\"")
+			} else {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+			}
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func (a *Authenticator) allowAddress(r *http.Request) bool {
+	if len(a.allowedIPs) == 0 {
+		return true
+	}
+	address := M.ParseSocksaddr(r.RemoteAddr).Addr.Unmap()
+	for _, prefix := range a.allowedIPs {
+		if prefix.Contains(address) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Authenticator) authenticate(r *http.Request) bool {
+	if len(a.tokens) == 0 && a.users == nil {
+		return true
+	}
+	if len(a.tokens) > 0 {
+		if token, loaded := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); loaded && a.verifyToken(token) {
+			return true
+		}
+		if a.queryParameter != "" && a.verifyToken(r.URL.Query().Get(a.queryParameter)) {
+			return true
+		}
+	}
+	if a.users != nil {
+		if username, password, loaded := r.BasicAuth(); loaded && a.users.Verify(username, password) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Authenticator) verifyToken(token string) bool {
+	if token == "" {
+		return false
+	}
+	var matched bool
+	for _, expected := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1 {
+			matched = true
+		}
+	}
+	return matched
+}
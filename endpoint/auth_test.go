@@ -0,0 +1,78 @@
+package endpoint
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing/common/auth"
+	"github.com/sagernet/sing/common/json/badoption"
+	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/srsc/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticator(t *testing.T) {
+	t.Parallel()
+	tokenOptions := &option.AuthOptions{Tokens: []string{"token1", "token2"}, QueryParameter: "token"}
+	userOptions := &option.AuthOptions{Users: []auth.User{{Username: "user", Password: "password"}}}
+	addressOptions := &option.AuthOptions{
+		Tokens:     []string{"token1"},
+		AllowedIPs: []badoption.Prefixable{badoption.Prefixable(netip.MustParsePrefix("10.0.0.0/8"))},
+	}
+	for _, testCase := range []struct {
+		name          string
+		options       *option.AuthOptions
+		path          string
+		header        string
+		username      string
+		password      string
+		remoteAddr    string
+		internal      bool
+		status        int
+		authenticates string
+	}{
+		{name: "disabled", status: http.StatusOK},
+		{name: "bearer token", options: tokenOptions, header: "Bearer token2", status: http.StatusOK},
+		{name: "query token", options: tokenOptions, path: "?token=token1", status: http.StatusOK},
+		{name: "missing token", options: tokenOptions, status: http.StatusUnauthorized, authenticates: "Bearer"},
+		{name: "invalid token", options: tokenOptions, header: "Bearer token3", status: http.StatusUnauthorized, authenticates: "Bearer"},
+		{name: "token prefix", options: tokenOptions, header: "Bearer token", status: http.StatusUnauthorized, authenticates: "Bearer"},
+		{name: "empty query token", options: tokenOptions, path: "?token=", status: http.StatusUnauthorized, authenticates: "Bearer"},
+		{name: "token without scheme", options: tokenOptions, header: "token1", status: http.StatusUnauthorized, authenticates: "Bearer"},
+		{name: "query token disabled", options: addressOptions, path: "?token=token1", remoteAddr: "10.0.0.1:1234", status: http.StatusUnauthorized, authenticates: "Bearer"},
+		{name: "basic", options: userOptions, username: "user", password: "password", status: http.StatusOK},
+		{name: "basic invalid password", options: userOptions, username: "user", password: "token1", status: http.StatusUnauthorized, authenticates: `Basic realm="srsc"`},
+		{name: "basic missing", options: userOptions, status: http.StatusUnauthorized, authenticates: `Basic realm="srsc"`},
+		{name: "allowed address", options: addressOptions, header: "Bearer token1", remoteAddr: "10.0.0.1:1234", status: http.StatusOK},
+		{name: "mapped allowed address", options: addressOptions, header: "Bearer token1", remoteAddr: "[::ffff:10.0.0.1]:1234", status: http.StatusOK},
+		{name: "disallowed address", options: addressOptions, header: "Bearer token1", remoteAddr: "192.168.1.1:1234", status: http.StatusForbidden},
+		{name: "internal", options: addressOptions, remoteAddr: "192.168.1.1:1234", internal: true, status: http.StatusOK},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			handler := NewAuthenticator(logger.NOP(), testCase.options).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			request := httptest.NewRequest("GET", "/test"+testCase.path, nil)
+			if testCase.header != "" {
+				request.Header.Set("Authorization", testCase.header)
+			}
+			if testCase.username != "" {
+				request.SetBasicAuth(testCase.username, testCase.password)
+			}
+			if testCase.remoteAddr != "" {
+				request.RemoteAddr = testCase.remoteAddr
+			}
+			if testCase.internal {
+				request = request.WithContext(ContextWithInternalRequest(request.Context()))
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			require.Equal(t, testCase.status, recorder.Code)
+			require.Equal(t, testCase.authenticates, recorder.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package endpoint
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/srsc/adapter"
+	C "github.com/sagernet/srsc/constant"
+	"github.com/sagernet/srsc/convertor/clash"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	encodingBrotli = "br"
+	encodingZstd   = "zstd"
+	encodingGzip   = "gzip"
+)
+
+// encodings are ordered by preference when accepted with the same quality.
+var encodings = []string{encodingBrotli, encodingZstd, encodingGzip}
+
+// compressible reports whether content converted with options is worth compressing,
+// sing-box binary and MRS rule-sets are compressed already.
+func compressible(options adapter.ConvertOptions) bool {
+	switch options.Options.TargetType {
+	case C.ConvertorTypeRuleSetBinary:
+		return false
+	case C.ConvertorTypeClashRuleProvider:
+		return clash.TargetFormat(options) != "mrs"
+	default:
+		return true
+	}
+}
+
+// encodeContent compresses content with all supported encodings, encodings that don't reduce the size are skipped.
+func encodeContent(content []byte) ([]adapter.EncodedContent, error) {
+	var encoded []adapter.EncodedContent
+	for _, encoding := range encodings {
+		compressed, err := compressContent(encoding, content)
+		if err != nil {
+			return nil, E.Cause(err, "compress content with ", encoding)
+		}
+		if len(compressed) >= len(content) {
+			continue
+		}
+		encoded = append(encoded, adapter.EncodedContent{
+			Encoding: encoding,
+			Content:  compressed,
+		})
+	}
+	return encoded, nil
+}
+
+func compressContent(encoding string, content []byte) ([]byte, error) {
+	var (
+		buffer bytes.Buffer
+		writer io.WriteCloser
+		err    error
+	)
+	switch encoding {
+	case encodingBrotli:
+		writer = brotli.NewWriterLevel(&buffer, 9)
+	case encodingZstd:
+		writer, err = zstd.NewWriter(&buffer, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+	case encodingGzip:
+		writer, err = gzip.NewWriterLevel(&buffer, gzip.BestCompression)
+	default:
+		return nil, E.New("unknown encoding: ", encoding)
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, err = writer.Write(content)
+	if err != nil {
+		return nil, err
+	}
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// negotiateEncoding returns the encoded content preferred by Accept-Encoding of the request, or nil if none is accepted.
+func negotiateEncoding(r *http.Request, encoded []adapter.EncodedContent) *adapter.EncodedContent {
+	acceptEncoding := r.Header.Get("Accept-Encoding")
+	if acceptEncoding == "" {
+		return nil
+	}
+	qualities := make(map[string]float64)
+	for _, item := range strings.Split(acceptEncoding, ",") {
+		encoding, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		encoding = strings.ToLower(strings.TrimSpace(encoding))
+		quality := 1.0
+		if qualityString, loaded := strings.CutPrefix(strings.TrimSpace(params), "q="); loaded {
+			parsedQuality, err := strconv.ParseFloat(qualityString, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsedQuality
+		}
+		qualities[encoding] = quality
+	}
+	var (
+		preferred        *adapter.EncodedContent
+		preferredQuality float64
+	)
+	for _, encoding := range encodings {
+		quality, loaded := qualities[encoding]
+		if !loaded {
+			quality, loaded = qualities["*"]
+		}
+		if !loaded || quality <= preferredQuality {
+			continue
+		}
+		for index := range encoded {
+			if encoded[index].Encoding == encoding {
+				preferred = &encoded[index]
+				preferredQuality = quality
+				break
+			}
+		}
+	}
+	return preferred
+}
+
+// encodedEtag derives the entity tag of the encoded representation from the entity tag of the content.
+func encodedEtag(contentEtag string, encoding string) string {
+	return strings.TrimSuffix(contentEtag, "\"") + "-" + encoding + "\""
+}
@@ -0,0 +1,83 @@
+package endpoint
+
+import (
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sagernet/srsc/adapter"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	t.Parallel()
+	allEncoded := []adapter.EncodedContent{
+		{Encoding: encodingBrotli},
+		{Encoding: encodingZstd},
+		{Encoding: encodingGzip},
+	}
+	gzipEncoded := []adapter.EncodedContent{
+		{Encoding: encodingGzip},
+	}
+	for _, testCase := range []struct {
+		name           string
+		acceptEncoding string
+		encoded        []adapter.EncodedContent
+		expected       string
+	}{
+		{"missing header", "", allEncoded, ""},
+		{"identity", "identity", allEncoded, ""},
+		{"single", "gzip", allEncoded, encodingGzip},
+		{"preference order", "gzip, zstd, br", allEncoded, encodingBrotli},
+		{"case insensitive", "GZIP", allEncoded, encodingGzip},
+		{"quality", "br;q=0.5, gzip;q=0.8", allEncoded, encodingGzip},
+		{"quality with spaces", "br; q=0.5, zstd ;q=0.9", allEncoded, encodingZstd},
+		{"zero quality", "br;q=0, gzip", allEncoded, encodingGzip},
+		{"wildcard", "*", allEncoded, encodingBrotli},
+		{"wildcard with excluded", "*, br;q=0", allEncoded, encodingZstd},
+		{"invalid quality ignored", "br;q=x, gzip", allEncoded, encodingGzip},
+		{"not encoded", "br, zstd", gzipEncoded, ""},
+		{"fallback to encoded", "br, gzip;q=0.1", gzipEncoded, encodingGzip},
+		{"nothing encoded", "gzip", nil, ""},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			request := httptest.NewRequest("GET", "/", nil)
+			if testCase.acceptEncoding != "" {
+				request.Header.Set("Accept-Encoding", testCase.acceptEncoding)
+			}
+			preferred := negotiateEncoding(request, testCase.encoded)
+			if testCase.expected == "" {
+				require.Nil(t, preferred)
+			} else {
+				require.NotNil(t, preferred)
+				require.Equal(t, testCase.expected, preferred.Encoding)
+			}
+		})
+	}
+}
+
+func TestEncodeContent(t *testing.T) {
+	t.Parallel()
+	content := []byte(strings.Repeat("DOMAIN-SUFFIX,example.com\n", 64))
+	encoded, err := encodeContent(content)
+	require.NoError(t, err)
+	require.Len(t, encoded, len(encodings))
+	for index, encodedContent := range encoded {
+		require.Equal(t, encodings[index], encodedContent.Encoding)
+		require.Less(t, len(encodedContent.Content), len(content))
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(encoded[2].Content))
+	require.NoError(t, err)
+	decoded, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.Equal(t, content, decoded)
+
+	encoded, err = encodeContent([]byte("a"))
+	require.NoError(t, err)
+	require.Empty(t, encoded, "encodings that don't reduce the size are skipped")
+}
@@ -2,79 +2,341 @@ package endpoint
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 	F "github.com/sagernet/sing/common/format"
 	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/sing/contrab/freelru"
+	"github.com/sagernet/sing/contrab/maphash"
 	"github.com/sagernet/sing/service"
 	"github.com/sagernet/srsc/adapter"
 	C "github.com/sagernet/srsc/constant"
 	"github.com/sagernet/srsc/convertor"
+	"github.com/sagernet/srsc/convertor/clash"
+	"github.com/sagernet/srsc/metrics"
 	"github.com/sagernet/srsc/option"
 	"github.com/sagernet/srsc/source"
 
 	"github.com/go-chi/chi/v5"
+	"golang.org/x/sync/singleflight"
 )
 
-var _ http.Handler = (*FileEndpoint)(nil)
+var _ adapter.Endpoint = (*FileEndpoint)(nil)
 
 type FileEndpoint struct {
 	ctx             context.Context
 	logger          logger.ContextLogger
 	cache           adapter.Cache
 	resources       adapter.ResourceManager
+	metrics         *metrics.Registry
 	index           int
+	path            string
 	source          adapter.Source
 	sourceConvertor adapter.Convertor
-	targetConvertor adapter.Convertor
+	targets         []*fileTarget
+	excludes        []*ruleSource
+	staleIfError    bool
+	maxStale        time.Duration
+	fetchGroup      singleflight.Group
+	refreshInterval time.Duration
+	staticPaths     map[string]*refreshRequest
+	refreshPaths    freelru.Cache[string, *refreshRequest]
+	done            chan struct{}
+}
+
+type fileTarget struct {
+	format          string
+	convertor       adapter.Convertor
 	convertOptions  option.ConvertOptions
 	convertRequired bool
 }
 
-func NewFileEndpoint(ctx context.Context, logger logger.ContextLogger, index int, options option.FileEndpoint) (*FileEndpoint, error) {
+type refreshRequest struct {
+	target       *fileTarget
+	cachePath    string
+	excludePaths []string
+	metadata     C.Metadata
+}
+
+func NewFileEndpoint(ctx context.Context, logger logger.ContextLogger, index int, path string, options option.FileEndpoint) (*FileEndpoint, error) {
 	ep := &FileEndpoint{
 		ctx:             ctx,
 		logger:          logger,
 		cache:           service.FromContext[adapter.Cache](ctx),
 		resources:       service.FromContext[adapter.ResourceManager](ctx),
+		metrics:         service.FromContext[*metrics.Registry](ctx),
 		index:           index,
-		convertOptions:  options.ConvertOptions,
-		convertRequired: options.ConvertOptions.ConvertRequired(),
+		path:            path,
+		staleIfError:    options.StaleIfError,
+		maxStale:        options.MaxStale.Build(),
+		refreshInterval: options.RefreshInterval.Build(),
+		staticPaths:     make(map[string]*refreshRequest),
+		done:            make(chan struct{}),
+	}
+	if ep.refreshInterval > 0 {
+		ep.refreshPaths = common.Must1(freelru.NewSynced[string, *refreshRequest](C.RefreshPathCapacity, maphash.NewHasher[string]().Hash32))
+		ep.refreshPaths.SetLifetime(C.RefreshPathIdleTimeout)
 	}
 	endpointSource, err := source.New(ctx, options.SourceOptions)
 	if err != nil {
 		return nil, E.Cause(err, "create source")
 	}
-	ep.source = endpointSource
+	ep.source = metrics.NewSource(ctx, path, endpointSource)
 	sourceConvertor, loaded := convertor.Convertors[options.SourceType]
 	if !loaded {
 		return nil, E.New("unknown source type: ", options.SourceType)
 	}
 	ep.sourceConvertor = sourceConvertor
-	targetConvertor, loaded := convertor.Convertors[options.TargetType]
-	if !loaded {
-		return nil, E.New("unknown target type: ", options.TargetType)
+	targetOptions := options.Targets
+	if len(targetOptions) == 0 {
+		targetOptions = []option.TargetConvertOptions{options.TargetConvertOptions}
 	}
-	ep.targetConvertor = targetConvertor
+	for _, targetConvertOptions := range targetOptions {
+		targetConvertor, loaded := convertor.Convertors[targetConvertOptions.TargetType]
+		if !loaded {
+			return nil, E.New("unknown target type: ", targetConvertOptions.TargetType)
+		}
+		convertOptions := option.ConvertOptions{
+			SourceConvertOptions: options.SourceConvertOptions,
+			TargetConvertOptions: targetConvertOptions,
+		}
+		target := &fileTarget{
+			format:          targetFormat(targetConvertOptions),
+			convertor:       targetConvertor,
+			convertOptions:  convertOptions,
+			convertRequired: convertOptions.ConvertRequired() || len(options.Exclude) > 0,
+		}
+		for _, existing := range ep.targets {
+			if existing.format == target.format {
+				return nil, E.New("duplicate target format: ", target.format)
+			}
+		}
+		ep.targets = append(ep.targets, target)
+	}
+	excludes, err := newRuleSources(ctx, path, "exclude", options.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	ep.excludes = excludes
 	return ep, nil
 }
 
+// TargetFormats returns formats of targets of the endpoint, or nil if the endpoint has only one target.
+func (f *FileEndpoint) TargetFormats() []string {
+	if len(f.targets) == 1 {
+		return nil
+	}
+	return common.Map(f.targets, func(target *fileTarget) string {
+		return target.format
+	})
+}
+
+func (f *FileEndpoint) Start() error {
+	if f.refreshInterval == 0 {
+		return nil
+	}
+	if !strings.Contains(f.path, "{") {
+		cachePath, err := f.source.Path(nil)
+		if err != nil {
+			return E.Cause(err, "evaluate source path")
+		}
+		excludePaths, err := ruleSourcePaths(f.excludes, "exclude", nil)
+		if err != nil {
+			return err
+		}
+		for _, target := range f.targets {
+			f.staticPaths[f.cacheKey(target, cachePath, excludePaths, C.Metadata{})] = &refreshRequest{
+				target:       target,
+				cachePath:    cachePath,
+				excludePaths: excludePaths,
+			}
+		}
+	}
+	go f.loopRefresh()
+	return nil
+}
+
+func (f *FileEndpoint) Close() error {
+	select {
+	case <-f.done:
+	default:
+		close(f.done)
+	}
+	return nil
+}
+
+func (f *FileEndpoint) loopRefresh() {
+	f.refresh()
+	ticker := time.NewTicker(f.refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			f.refresh()
+		case <-f.done:
+			return
+		}
+	}
+}
+
+// loadRefreshPaths returns paths of the endpoint without templates and requested paths not idle.
+func (f *FileEndpoint) loadRefreshPaths() map[string]*refreshRequest {
+	refreshPaths := make(map[string]*refreshRequest, len(f.staticPaths))
+	for cacheKey, request := range f.staticPaths {
+		refreshPaths[cacheKey] = request
+	}
+	if f.refreshPaths != nil {
+		for _, cacheKey := range f.refreshPaths.Keys() {
+			if request, loaded := f.refreshPaths.Peek(cacheKey); loaded {
+				refreshPaths[cacheKey] = request
+			}
+		}
+	}
+	return refreshPaths
+}
+
+func (f *FileEndpoint) refresh() {
+	for cacheKey, request := range f.loadRefreshPaths() {
+		select {
+		case <-f.done:
+			return
+		default:
+		}
+		convertOptions := adapter.ConvertOptions{
+			Options:  request.target.convertOptions,
+			Metadata: request.metadata,
+		}
+		cachePath := request.cachePath
+		result, err, _ := f.fetchGroup.Do(cacheKey, func() (any, error) {
+			return f.fetch(request.target, cachePath, request.excludePaths, cacheKey, convertOptions, true)
+		})
+		if err != nil {
+			f.logger.Error("refresh endpoint ", f.path, " (", cachePath, "): ", err)
+			// requested paths are refreshed again once requested successfully
+			f.refreshPaths.Remove(cacheKey)
+		} else if staleErr := result.(*fetchResult).staleErr; staleErr != nil {
+			f.logger.Warn("refresh endpoint ", f.path, " (", cachePath, "): ", staleErr)
+		} else {
+			f.logger.Debug("refreshed endpoint ", f.path, " (", cachePath, ")")
+		}
+	}
+}
+
+// Refresh purges cached content of the endpoint and fetches paths refreshed in background again.
+func (f *FileEndpoint) Refresh() (int, error) {
+	purged, err := adapter.PurgeBinary(f.cache, F.ToString("file.", f.index, "."))
+	if err != nil {
+		return purged, E.Cause(err, "purge cache")
+	}
+	for cacheKey, request := range f.loadRefreshPaths() {
+		convertOptions := adapter.ConvertOptions{
+			Options:  request.target.convertOptions,
+			Metadata: request.metadata,
+		}
+		_, err, _ = f.fetchGroup.Do(cacheKey, func() (any, error) {
+			return f.fetch(request.target, request.cachePath, request.excludePaths, cacheKey, convertOptions, true)
+		})
+		if err != nil {
+			return purged, E.Cause(err, "refresh ", request.cachePath)
+		}
+	}
+	return purged, nil
+}
+
 func (f *FileEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	err := f.serveHTTP0(w, r)
 	if err != nil {
-		f.logger.Error("handle ", r.RemoteAddr, " - ", r.Header.Get("User-Agent"), " \"", r.Method, " ", r.URL, " ", r.Proto, "\": ", err)
+		f.logger.Error("handle ", r.RemoteAddr, " - ", r.Header.Get("User-Agent"), " \"", r.Method, " ", r.URL.Path, " ", r.Proto, "\": ", err)
 	} else {
-		f.logger.Debug("accepted ", r.RemoteAddr, " - ", r.Header.Get("User-Agent"), " \"", r.Method, " ", r.URL, " ", r.Proto, "\"")
+		f.logger.Debug("accepted ", r.RemoteAddr, " - ", r.Header.Get("User-Agent"), " \"", r.Method, " ", r.URL.Path, " ", r.Proto, "\"")
 	}
 }
 
 func (f *FileEndpoint) serveHTTP0(w http.ResponseWriter, r *http.Request) error {
+	metadata := C.DetectMetadata(r.UserAgent())
+	if len(f.targets) > 1 {
+		w.Header().Add("Vary", "Accept, User-Agent")
+	}
+	target, err := negotiateTarget(f.targets, r, metadata)
+	if err != nil {
+		writeError(w, err)
+		return err
+	}
 	convertOptions := adapter.ConvertOptions{
-		Options:  f.convertOptions,
-		Metadata: C.DetectMetadata(r.UserAgent()),
+		Options:  target.convertOptions,
+		Metadata: metadata,
+	}
+	if len(f.targets) == 1 && convertor.Variant(convertOptions) != "" {
+		w.Header().Add("Vary", "User-Agent")
+	}
+	urlParams := routeParams(r)
+	cachePath, err := f.source.Path(urlParams)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return E.Cause(err, "evaluate source path")
+	}
+	excludePaths, err := ruleSourcePaths(f.excludes, "exclude", urlParams)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return err
+	}
+	cacheKey := f.cacheKey(target, cachePath, excludePaths, metadata)
+	result, err, _ := f.fetchGroup.Do(cacheKey, func() (any, error) {
+		return f.fetch(target, cachePath, excludePaths, cacheKey, convertOptions, false)
+	})
+	if err != nil {
+		writeError(w, err)
+		return err
+	}
+	if f.refreshInterval > 0 && f.staticPaths[cacheKey] == nil {
+		f.refreshPaths.Add(cacheKey, &refreshRequest{
+			target:       target,
+			cachePath:    cachePath,
+			excludePaths: excludePaths,
+			metadata:     convertOptions.Metadata,
+		})
+	}
+	fetched := result.(*fetchResult)
+	if fetched.staleErr != nil {
+		return f.writeStale(w, r, target, fetched.binary, convertOptions, fetched.staleErr)
+	}
+	return f.writeCache(w, r, target, fetched.binary, convertOptions)
+}
+
+type fetchResult struct {
+	binary   *adapter.SavedBinary
+	staleErr error
+}
+
+type statusError struct {
+	statusCode int
+	err        error
+}
+
+func (e *statusError) Error() string {
+	return e.err.Error()
+}
+
+func (e *statusError) Unwrap() error {
+	return e.err
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		w.WriteHeader(statusErr.statusCode)
+	} else {
+		w.WriteHeader(http.StatusInternalServerError)
 	}
+}
+
+func routeParams(r *http.Request) map[string]string {
 	var urlParams map[string]string // TODO: improve performance
 	rawURLParams := chi.RouteContext(r.Context()).URLParams
 	if len(rawURLParams.Keys) > 0 {
@@ -83,87 +345,294 @@ func (f *FileEndpoint) serveHTTP0(w http.ResponseWriter, r *http.Request) error
 			urlParams[key] = rawURLParams.Values[i]
 		}
 	}
-	cachePath, err := f.source.Path(urlParams)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		return E.Cause(err, "evaluate source path")
+	return urlParams
+}
+
+func (f *FileEndpoint) cacheKey(target *fileTarget, cachePath string, excludePaths []string, metadata C.Metadata) string {
+	var cacheKey string
+	if len(f.targets) > 1 {
+		cacheKey = F.ToString("file.", f.index, ".", target.format, ".", cachePath)
+	} else {
+		cacheKey = F.ToString("file.", f.index, ".", cachePath)
+	}
+	if len(excludePaths) > 0 {
+		cacheKey += "-" + strings.Join(excludePaths, ",")
+	}
+	if variant := convertor.Variant(adapter.ConvertOptions{Options: target.convertOptions, Metadata: metadata}); variant != "" {
+		cacheKey += "@" + variant
 	}
-	cacheKey := F.ToString("file.", f.index, ".", cachePath)
+	return cacheKey
+}
+
+func (f *FileEndpoint) fetch(target *fileTarget, cachePath string, excludePaths []string, cacheKey string, convertOptions adapter.ConvertOptions, force bool) (*fetchResult, error) {
 	cachedBinary, err := f.cache.LoadBinary(cacheKey)
 	if err != nil && !os.IsNotExist(err) {
-		w.WriteHeader(http.StatusInternalServerError)
-		return E.Cause(err, "load cache binary")
+		return nil, E.Cause(err, "load cache binary")
+	}
+	var (
+		excludes        *ruleSourcesResult
+		excludeStaleErr error
+	)
+	if len(f.excludes) > 0 {
+		excludes, err = fetchRuleSources(f.cache, f.excludes, "exclude", excludePaths, F.ToString("file.", f.index), force)
+		if err != nil {
+			if f.staleAcceptable(cachedBinary) {
+				return &fetchResult{binary: cachedBinary, staleErr: err}, nil
+			}
+			return nil, err
+		}
+		excludeStaleErr = excludes.staleErr
 	}
+	excludeChanged := excludes != nil && cachedBinary != nil && cachedBinary.ExcludeEtag != excludes.fingerprint
 	lastUpdated := f.source.LastUpdated(cachePath)
-	if cachedBinary != nil && !lastUpdated.IsZero() && cachedBinary.LastUpdated.Equal(lastUpdated) {
-		return f.writeCache(w, cachedBinary, convertOptions)
+	if cachedBinary != nil && !excludeChanged && !lastUpdated.IsZero() && cachedBinary.LastUpdated.Equal(lastUpdated) {
+		return &fetchResult{binary: cachedBinary, staleErr: excludeStaleErr}, nil
 	}
 
 	var fetchBody adapter.FetchRequestBody
-	if cachedBinary != nil {
+	if cachedBinary != nil && !excludeChanged {
 		fetchBody.ETag = cachedBinary.LastEtag
-		fetchBody.LastUpdated = cachedBinary.LastUpdated
+		if !force {
+			fetchBody.LastUpdated = cachedBinary.LastUpdated
+		}
 	}
 	response, err := f.source.Fetch(cachePath, fetchBody)
 	if err != nil {
-		w.WriteHeader(http.StatusBadGateway)
-		return E.Cause(err, "fetch source")
+		err = E.Cause(err, "fetch source")
+		if f.staleAcceptable(cachedBinary) {
+			return &fetchResult{binary: cachedBinary, staleErr: err}, nil
+		}
+		return nil, &statusError{http.StatusBadGateway, err}
 	}
 	if response.NotModified {
 		if cachedBinary == nil {
-			w.WriteHeader(http.StatusBadGateway)
-			return E.New("fetch source: unexpected not modified response")
+			return nil, &statusError{http.StatusBadGateway, E.New("fetch source: unexpected not modified response")}
 		}
 		if response.LastUpdated != cachedBinary.LastUpdated {
 			cachedBinary.LastUpdated = response.LastUpdated
 			err = f.cache.SaveBinary(cacheKey, cachedBinary)
 			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				return E.Cause(err, "save cache binary")
+				return nil, E.Cause(err, "save cache binary")
 			}
 		}
-		return f.writeCache(w, cachedBinary, convertOptions)
+		return &fetchResult{binary: cachedBinary, staleErr: excludeStaleErr}, nil
 	}
 	if len(response.Content) == 0 {
-		w.WriteHeader(http.StatusBadGateway)
-		return E.Cause(err, "fetch source: empty content")
+		err = E.New("fetch source: empty content")
+		if f.staleAcceptable(cachedBinary) {
+			return &fetchResult{binary: cachedBinary, staleErr: err}, nil
+		}
+		return nil, &statusError{http.StatusBadGateway, err}
 	}
 	binary := response.Content
-	if f.convertRequired {
+	var diagnostics *adapter.Diagnostics
+	if target.convertRequired || convertor.Variant(convertOptions) != "" {
+		diagnostics = adapter.NewDiagnostics()
+		convertOptions.Diagnostics = diagnostics
+		convertStartAt := time.Now()
 		var rules []adapter.Rule
 		rules, err = f.sourceConvertor.From(f.ctx, response.Content, convertOptions)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			return E.Cause(err, "decode source")
+			err = E.Cause(err, "decode source")
+			if f.staleAcceptable(cachedBinary) {
+				return &fetchResult{binary: cachedBinary, staleErr: err}, nil
+			}
+			return nil, err
 		}
-		binary, err = f.targetConvertor.To(f.ctx, rules, convertOptions)
+		if excludes != nil {
+			var excludeRules []adapter.Rule
+			excludeRules, err = decodeRuleSources(f.ctx, f.excludes, "exclude", excludes.contents, target.convertOptions.TargetConvertOptions, convertOptions.Metadata, nil)
+			if err != nil {
+				return nil, err
+			}
+			rules = adapter.ExcludeRules(rules, excludeRules)
+		}
+		rules, err = convertor.OptimizeRules(f.ctx, f.logger, rules, convertOptions)
+		if err != nil {
+			return nil, err
+		}
+		binary, err = target.convertor.To(f.ctx, rules, convertOptions)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			return E.Cause(err, "encode target")
+			return nil, E.Cause(err, "encode target")
 		}
+		f.metrics.ObserveConversion(f.sourceConvertor.Type(), target.convertor.Type(), time.Since(convertStartAt))
 	}
-	cachedBinary = &adapter.SavedBinary{
-		Content:     binary,
-		LastUpdated: response.LastUpdated,
-		LastEtag:    response.ETag,
+	savedBinary := &adapter.SavedBinary{
+		Content:      binary,
+		LastUpdated:  response.LastUpdated,
+		LastEtag:     response.ETag,
+		ContentEtag:  adapter.ContentEtag(binary),
+		LastModified: response.LastUpdated,
+		Diagnostics:  diagnostics.Entries(),
 	}
-	err = f.cache.SaveBinary(cacheKey, cachedBinary)
+	if excludes != nil {
+		savedBinary.ExcludeEtag = excludes.fingerprint
+	}
+	if compressible(convertOptions) {
+		savedBinary.Encoded, err = encodeContent(binary)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cachedBinary != nil && cachedBinary.ContentEtag == savedBinary.ContentEtag && !cachedBinary.LastModified.IsZero() {
+		savedBinary.LastModified = cachedBinary.LastModified
+	}
+	err = f.cache.SaveBinary(cacheKey, savedBinary)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		return E.Cause(err, "save cache binary")
+		return nil, E.Cause(err, "save cache binary")
 	}
-	return f.writeCache(w, cachedBinary, convertOptions)
+	return &fetchResult{binary: savedBinary, staleErr: excludeStaleErr}, nil
 }
 
-func (f *FileEndpoint) writeCache(w http.ResponseWriter, cachedBinary *adapter.SavedBinary, convertOptions adapter.ConvertOptions) error {
-	w.Header().Set("Content-Type", f.targetConvertor.ContentType(convertOptions)+"; charset=utf-8")
-	w.Header().Set("Content-Length", F.ToString(len(cachedBinary.Content)))
-	if cachedBinary.LastEtag != "" {
-		w.Header().Set("ETag", cachedBinary.LastEtag)
+// CachedBinary returns the cache key and the cached content of the endpoint for urlParams,
+// content will be fetched first if not cached.
+func (f *FileEndpoint) CachedBinary(urlParams map[string]string, metadata C.Metadata) (string, *adapter.SavedBinary, error) {
+	cachePath, err := f.source.Path(urlParams)
+	if err != nil {
+		return "", nil, &statusError{http.StatusBadRequest, E.Cause(err, "evaluate source path")}
 	}
-	_, err := w.Write(cachedBinary.Content)
+	excludePaths, err := ruleSourcePaths(f.excludes, "exclude", urlParams)
+	if err != nil {
+		return "", nil, &statusError{http.StatusBadRequest, err}
+	}
+	target := f.cachedTarget(metadata)
+	cacheKey := f.cacheKey(target, cachePath, excludePaths, metadata)
+	cachedBinary, err := f.cache.LoadBinary(cacheKey)
+	metrics.ObserveLoad(f.cache, cachedBinary, err)
+	if err != nil && !os.IsNotExist(err) {
+		return "", nil, E.Cause(err, "load cache binary")
+	}
+	if cachedBinary == nil {
+		convertOptions := adapter.ConvertOptions{
+			Options:  target.convertOptions,
+			Metadata: metadata,
+		}
+		result, err, _ := f.fetchGroup.Do(cacheKey, func() (any, error) {
+			return f.fetch(target, cachePath, excludePaths, cacheKey, convertOptions, false)
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		cachedBinary = result.(*fetchResult).binary
+	}
+	return cacheKey, cachedBinary, nil
+}
+
+// CachedRules decodes the cached content of the endpoint for urlParams with its target convertor.
+func (f *FileEndpoint) CachedRules(urlParams map[string]string, metadata C.Metadata) (string, *adapter.SavedBinary, []adapter.Rule, error) {
+	cacheKey, cachedBinary, err := f.CachedBinary(urlParams, metadata)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	target := f.cachedTarget(metadata)
+	rules, err := target.convertor.From(f.ctx, cachedBinary.Content, adapter.ConvertOptions{
+		Options: option.ConvertOptions{
+			SourceConvertOptions: target.sourceOptions(metadata),
+		},
+		Metadata: metadata,
+	})
+	if err != nil {
+		return "", nil, nil, E.Cause(err, "decode cached content")
+	}
+	return cacheKey, cachedBinary, rules, nil
+}
+
+// cachedTarget returns the target of cached content for metadata,
+// which is the target preferred by the client if detected, or the first target.
+func (f *FileEndpoint) cachedTarget(metadata C.Metadata) *fileTarget {
+	if target := clientTarget(f.targets, metadata); target != nil {
+		return target
+	}
+	return f.targets[0]
+}
+
+// sourceOptions returns source convert options to decode content encoded by the target convertor.
+func (t *fileTarget) sourceOptions(metadata C.Metadata) option.SourceConvertOptions {
+	targetOptions := t.convertOptions.TargetConvertOptions
+	var sourceOptions option.SourceConvertOptions
+	sourceOptions.SourceType = targetOptions.TargetType
+	sourceOptions.ClashOptions.SourceFormat = clash.TargetFormat(adapter.ConvertOptions{Options: t.convertOptions, Metadata: metadata})
+	sourceOptions.ClashOptions.SourceBehavior = targetOptions.ClashOptions.TargetBehavior
+	sourceOptions.SurgeOptions.SourceBehavior = targetOptions.SurgeOptions.TargetBehavior
+	if t.convertOptions.SourceType == targetOptions.TargetType {
+		sourceOptions.AdGuardOptions = t.convertOptions.SourceConvertOptions.AdGuardOptions
+	}
+	return sourceOptions
+}
+
+func (f *FileEndpoint) staleAcceptable(cachedBinary *adapter.SavedBinary) bool {
+	if !f.staleIfError || cachedBinary == nil {
+		return false
+	}
+	return f.maxStale == 0 || time.Since(cachedBinary.LastUpdated) <= f.maxStale
+}
+
+func (f *FileEndpoint) writeStale(w http.ResponseWriter, r *http.Request, target *fileTarget, cachedBinary *adapter.SavedBinary, convertOptions adapter.ConvertOptions, err error) error {
+	f.logger.Warn("serve stale content for endpoint ", f.path, ": ", err)
+	metrics.ObserveStale(f.cache)
+	return writeStale(w, r, cachedBinary, target.convertor.ContentType(convertOptions))
+}
+
+func (f *FileEndpoint) writeCache(w http.ResponseWriter, r *http.Request, target *fileTarget, cachedBinary *adapter.SavedBinary, convertOptions adapter.ConvertOptions) error {
+	return writeCache(w, r, cachedBinary, target.convertor.ContentType(convertOptions))
+}
+
+func writeStale(w http.ResponseWriter, r *http.Request, cachedBinary *adapter.SavedBinary, contentType string) error {
+	w.Header().Set("Warning", "111 - \"Revalidation Failed\"")
+	w.Header().Set("X-Srsc-Stale", "true")
+	return writeCache(w, r, cachedBinary, contentType)
+}
+
+func writeCache(w http.ResponseWriter, r *http.Request, cachedBinary *adapter.SavedBinary, contentType string) error {
+	contentEtag := cachedBinary.ContentEtag
+	if contentEtag == "" {
+		contentEtag = adapter.ContentEtag(cachedBinary.Content)
+	}
+	content := cachedBinary.Content
+	if len(cachedBinary.Encoded) > 0 {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if encoded := negotiateEncoding(r, cachedBinary.Encoded); encoded != nil {
+			content = encoded.Content
+			contentEtag = encodedEtag(contentEtag, encoded.Encoding)
+			w.Header().Set("Content-Encoding", encoded.Encoding)
+		}
+	}
+	w.Header().Set("ETag", contentEtag)
+	droppedLines, droppedRules := adapter.DiagnosticCount(cachedBinary.Diagnostics)
+	w.Header().Set("X-Srsc-Dropped-Lines", F.ToString(droppedLines))
+	w.Header().Set("X-Srsc-Dropped-Rules", F.ToString(droppedRules))
+	if !cachedBinary.LastModified.IsZero() {
+		w.Header().Set("Last-Modified", cachedBinary.LastModified.UTC().Format(http.TimeFormat))
+	}
+	if isNotModified(r, contentEtag, cachedBinary.LastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	w.Header().Set("Content-Type", contentType+"; charset=utf-8")
+	w.Header().Set("Content-Length", F.ToString(len(content)))
+	_, err := w.Write(content)
 	if err != nil {
 		return E.Cause(err, "write cached content")
 	}
 	return nil
 }
+
+func isNotModified(r *http.Request, contentEtag string, lastModified time.Time) bool {
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		for _, etag := range strings.Split(ifNoneMatch, ",") {
+			etag = strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+			if etag == "*" || etag == contentEtag {
+				return true
+			}
+		}
+		return false
+	}
+	if lastModified.IsZero() {
+		return false
+	}
+	ifModifiedSince, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(ifModifiedSince)
+}
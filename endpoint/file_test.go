@@ -0,0 +1,161 @@
+package endpoint
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/sing/service"
+	"github.com/sagernet/srsc/adapter"
+	"github.com/sagernet/srsc/cache"
+	C "github.com/sagernet/srsc/constant"
+	"github.com/sagernet/srsc/option"
+	"github.com/sagernet/srsc/resource"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestContext() context.Context {
+	ctx := service.ContextWithDefaultRegistry(context.Background())
+	service.MustRegister[adapter.Cache](ctx, cache.NewMemory(time.Hour))
+	service.MustRegister[adapter.ResourceManager](ctx, common.Must1(resource.NewManager(ctx, logger.NOP(), option.ResourceOptions{})))
+	return ctx
+}
+
+func newTestFileEndpoint(t *testing.T, source *testSource, options option.FileEndpoint) *FileEndpoint {
+	options.Source = C.EndpointSourceLocal
+	options.LocalOptions.Path = "test"
+	fileEndpoint, err := NewFileEndpoint(newTestContext(), logger.NOP(), 0, "/test", options)
+	require.NoError(t, err)
+	fileEndpoint.source = source
+	return fileEndpoint
+}
+
+func TestFileEndpointCoalesce(t *testing.T) {
+	t.Parallel()
+	source := &testSource{content: []byte("DOMAIN,a.com\n"), delay: 100 * time.Millisecond}
+	var options option.FileEndpoint
+	options.SourceType = C.ConvertorTypeClashRuleProvider
+	options.SourceConvertOptions.ClashOptions.SourceFormat = "text"
+	options.SourceConvertOptions.ClashOptions.SourceBehavior = "classical"
+	options.TargetType = C.ConvertorTypeRuleSetSource
+	fileEndpoint := newTestFileEndpoint(t, source, options)
+	var (
+		waitGroup sync.WaitGroup
+		access    sync.Mutex
+		contents  []string
+		errors    []error
+	)
+	for range 8 {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			_, cachedBinary, err := fileEndpoint.CachedBinary(nil, C.Metadata{})
+			access.Lock()
+			defer access.Unlock()
+			if err != nil {
+				errors = append(errors, err)
+				return
+			}
+			contents = append(contents, string(cachedBinary.Content))
+		}()
+	}
+	waitGroup.Wait()
+	require.Empty(t, errors)
+	require.EqualValues(t, 1, source.fetches.Load())
+	require.Len(t, contents, 8)
+	for _, content := range contents {
+		require.Equal(t, contents[0], content)
+	}
+	_, _, err := fileEndpoint.CachedBinary(nil, C.Metadata{})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, source.fetches.Load())
+}
+
+func TestWriteCacheConditional(t *testing.T) {
+	t.Parallel()
+	lastModified := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	cachedBinary := &adapter.SavedBinary{
+		Content:      []byte("content"),
+		ContentEtag:  `"abc"`,
+		LastModified: lastModified.Add(500 * time.Millisecond),
+	}
+	for _, testCase := range []struct {
+		name            string
+		ifNoneMatch     string
+		ifModifiedSince string
+		status          int
+	}{
+		{name: "unconditional", status: http.StatusOK},
+		{name: "etag", ifNoneMatch: `"abc"`, status: http.StatusNotModified},
+		{name: "weak etag", ifNoneMatch: `W/"abc"`, status: http.StatusNotModified},
+		{name: "etag list", ifNoneMatch: `"def", "abc"`, status: http.StatusNotModified},
+		{name: "any etag", ifNoneMatch: "*", status: http.StatusNotModified},
+		{name: "etag mismatch", ifNoneMatch: `"def"`, status: http.StatusOK},
+		{name: "etag over date", ifNoneMatch: `"def"`, ifModifiedSince: lastModified.Format(http.TimeFormat), status: http.StatusOK},
+		{name: "not modified since", ifModifiedSince: lastModified.Format(http.TimeFormat), status: http.StatusNotModified},
+		{name: "modified since", ifModifiedSince: lastModified.Add(-time.Second).Format(http.TimeFormat), status: http.StatusOK},
+		{name: "invalid date", ifModifiedSince: "yesterday", status: http.StatusOK},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			request := httptest.NewRequest("GET", "/test", nil)
+			if testCase.ifNoneMatch != "" {
+				request.Header.Set("If-None-Match", testCase.ifNoneMatch)
+			}
+			if testCase.ifModifiedSince != "" {
+				request.Header.Set("If-Modified-Since", testCase.ifModifiedSince)
+			}
+			recorder := httptest.NewRecorder()
+			require.NoError(t, writeCache(recorder, request, cachedBinary, "text/plain"))
+			require.Equal(t, testCase.status, recorder.Code)
+			require.Equal(t, `"abc"`, recorder.Header().Get("ETag"))
+			require.Equal(t, lastModified.Format(http.TimeFormat), recorder.Header().Get("Last-Modified"))
+			if testCase.status == http.StatusOK {
+				require.Equal(t, "content", recorder.Body.String())
+			} else {
+				require.Zero(t, recorder.Body.Len())
+			}
+		})
+	}
+}
+
+func TestFileEndpointStrict(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name    string
+		content string
+		status  int
+	}{
+		{"converted", "DOMAIN,a.com\n", http.StatusOK},
+		{"dropped", "DOMAIN,a.com\nPROCESS-NAME,curl\n", http.StatusInternalServerError},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			var options option.FileEndpoint
+			options.SourceType = C.ConvertorTypeClashRuleProvider
+			options.SourceConvertOptions.ClashOptions.SourceFormat = "text"
+			options.SourceConvertOptions.ClashOptions.SourceBehavior = "classical"
+			options.TargetType = C.ConvertorTypeHostsFile
+			options.Strict = true
+			fileEndpoint := newTestFileEndpoint(t, &testSource{content: []byte(testCase.content)}, options)
+			router := chi.NewRouter()
+			router.Get("/test", fileEndpoint.ServeHTTP)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest("GET", "/test", nil))
+			require.Equal(t, testCase.status, recorder.Code)
+			_, _, err := fileEndpoint.CachedBinary(nil, C.Metadata{})
+			if testCase.status == http.StatusOK {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, "strict mode: dropped rule")
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package endpoint
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/netip"
+	"strconv"
+
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/sing/contrab/freelru"
+	"github.com/sagernet/sing/contrab/maphash"
+	"github.com/sagernet/srsc/adapter"
+	C "github.com/sagernet/srsc/constant"
+	"github.com/sagernet/srsc/convertor/clash"
+	"github.com/sagernet/srsc/match"
+)
+
+var _ adapter.Endpoint = (*MatchEndpoint)(nil)
+
+// matcherCapacity is the maximum number of cached matchers of each match endpoint,
+// a matcher is cached for each cache key of the file endpoint.
+const matcherCapacity = 64
+
+type MatchEndpoint struct {
+	ctx      context.Context
+	logger   logger.ContextLogger
+	file     *FileEndpoint
+	matchers freelru.Cache[string, cachedMatcher]
+}
+
+type cachedMatcher struct {
+	contentEtag string
+	matcher     *match.Matcher
+}
+
+type matchResponse struct {
+	Endpoint string             `json:"endpoint"`
+	Results  []matchQueryResult `json:"results"`
+}
+
+type matchQueryResult struct {
+	Query string `json:"query"`
+	match.Result
+}
+
+func NewMatchEndpoint(ctx context.Context, logger logger.ContextLogger, file *FileEndpoint) *MatchEndpoint {
+	return &MatchEndpoint{
+		ctx:      ctx,
+		logger:   logger,
+		file:     file,
+		matchers: common.Must1(freelru.NewSynced[string, cachedMatcher](matcherCapacity, maphash.NewHasher[string]().Hash32)),
+	}
+}
+
+func (m *MatchEndpoint) Start() error {
+	return nil
+}
+
+func (m *MatchEndpoint) Close() error {
+	return nil
+}
+
+func (m *MatchEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	err := m.serveHTTP0(w, r)
+	if err != nil {
+		m.logger.Error("handle ", r.RemoteAddr, " - ", r.Header.Get("User-Agent"), " \"", r.Method, " ", r.URL.Path, " ", r.Proto, "\": ", err)
+	} else {
+		m.logger.Debug("accepted ", r.RemoteAddr, " - ", r.Header.Get("User-Agent"), " \"", r.Method, " ", r.URL.Path, " ", r.Proto, "\"")
+	}
+}
+
+func (m *MatchEndpoint) serveHTTP0(w http.ResponseWriter, r *http.Request) error {
+	queries, err := parseMatchQueries(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return err
+	}
+	matcher, err := m.loadMatcher(routeParams(r), C.DetectMetadata(r.UserAgent()))
+	if err != nil {
+		writeError(w, err)
+		return err
+	}
+	response := matchResponse{
+		Endpoint: m.file.path,
+		Results:  make([]matchQueryResult, 0, len(queries)),
+	}
+	for _, query := range queries {
+		response.Results = append(response.Results, matchQueryResult{
+			Query:  query.String(),
+			Result: matcher.Match(query),
+		})
+	}
+	buffer := new(bytes.Buffer)
+	err = json.NewEncoder(buffer).Encode(response)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return E.Cause(err, "encode response")
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_, err = w.Write(buffer.Bytes())
+	if err != nil {
+		return E.Cause(err, "write response")
+	}
+	return nil
+}
+
+func (m *MatchEndpoint) loadMatcher(urlParams map[string]string, metadata C.Metadata) (*match.Matcher, error) {
+	cacheKey, cachedBinary, rules, err := m.file.CachedRules(urlParams, metadata)
+	if err != nil {
+		return nil, err
+	}
+	if cached, loaded := m.matchers.Get(cacheKey); loaded && cached.contentEtag == cachedBinary.ContentEtag {
+		return cached.matcher, nil
+	}
+	rules, err = adapter.EmbedResourceRules(m.ctx, rules)
+	if err != nil {
+		return nil, err
+	}
+	content := cachedBinary.Content
+	target := m.file.cachedTarget(metadata)
+	if target.convertor.Type() == C.ConvertorTypeRuleSetBinary || clash.TargetFormat(adapter.ConvertOptions{Options: target.convertOptions, Metadata: metadata}) == "mrs" {
+		content = nil
+	}
+	matcher, err := match.New(m.ctx, rules, content)
+	if err != nil {
+		return nil, err
+	}
+	m.matchers.Add(cacheKey, cachedMatcher{
+		contentEtag: cachedBinary.ContentEtag,
+		matcher:     matcher,
+	})
+	return matcher, nil
+}
+
+func parseMatchQueries(r *http.Request) ([]match.Query, error) {
+	query := r.URL.Query()
+	var port uint16
+	if portString := query.Get("port"); portString != "" {
+		portValue, err := strconv.ParseUint(portString, 10, 16)
+		if err != nil {
+			return nil, E.Cause(err, "parse port")
+		}
+		port = uint16(portValue)
+	}
+	network := query.Get("network")
+	var queries []match.Query
+	for _, domain := range query["domain"] {
+		queries = append(queries, match.Query{
+			Domain:  domain,
+			Port:    port,
+			Network: network,
+		})
+	}
+	for _, ipString := range query["ip"] {
+		address, err := netip.ParseAddr(ipString)
+		if err != nil {
+			return nil, E.Cause(err, "parse IP address")
+		}
+		queries = append(queries, match.Query{
+			IP:      address,
+			Port:    port,
+			Network: network,
+		})
+	}
+	if len(queries) == 0 {
+		return nil, E.New("missing domain or IP to match")
+	}
+	return queries, nil
+}
@@ -0,0 +1,64 @@
+package endpoint
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/logger"
+	C "github.com/sagernet/srsc/constant"
+	"github.com/sagernet/srsc/match"
+	"github.com/sagernet/srsc/option"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchEndpoint(t *testing.T) {
+	t.Parallel()
+	var options option.FileEndpoint
+	options.SourceType = C.ConvertorTypeClashRuleProvider
+	options.SourceConvertOptions.ClashOptions.SourceFormat = "text"
+	options.SourceConvertOptions.ClashOptions.SourceBehavior = "classical"
+	options.TargetType = C.ConvertorTypeClashRuleProvider
+	options.TargetConvertOptions.ClashOptions.TargetFormat = "text"
+	options.TargetConvertOptions.ClashOptions.TargetBehavior = "classical"
+	fileEndpoint := newTestFileEndpoint(t, &testSource{content: []byte("# rules\nDOMAIN-SUFFIX,a.com\nIP-CIDR,10.0.0.0/8,no-resolve\n")}, options)
+	router := chi.NewRouter()
+	router.Get("/test/match", NewMatchEndpoint(fileEndpoint.ctx, logger.NOP(), fileEndpoint).ServeHTTP)
+	for _, testCase := range []struct {
+		name     string
+		query    string
+		status   int
+		expected []matchQueryResult
+	}{
+		{
+			name:   "domain and ip",
+			query:  "?domain=WWW.A.com&ip=10.1.2.3&domain=b.com",
+			status: http.StatusOK,
+			expected: []matchQueryResult{
+				{Query: "WWW.A.com", Result: match.Result{Matched: true, Item: "domain_suffix=a.com", Line: 2, SourceLine: "DOMAIN-SUFFIX,a.com"}},
+				{Query: "b.com", Result: match.Result{RuleIndex: -1}},
+				{Query: "10.1.2.3", Result: match.Result{Matched: true, Item: "ip_cidr=10.0.0.0/8", Line: 3, SourceLine: "IP-CIDR,10.0.0.0/8,no-resolve"}},
+			},
+		},
+		{name: "missing query", status: http.StatusBadRequest},
+		{name: "invalid ip", query: "?ip=10.0.0", status: http.StatusBadRequest},
+		{name: "invalid port", query: "?domain=a.com&port=65536", status: http.StatusBadRequest},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest("GET", "/test/match"+testCase.query, nil))
+			require.Equal(t, testCase.status, recorder.Code)
+			if testCase.status != http.StatusOK {
+				return
+			}
+			var response matchResponse
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+			require.Equal(t, "/test", response.Endpoint)
+			require.Equal(t, testCase.expected, response.Results)
+		})
+	}
+}
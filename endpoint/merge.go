@@ -0,0 +1,254 @@
+package endpoint
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/sing/service"
+	"github.com/sagernet/srsc/adapter"
+	C "github.com/sagernet/srsc/constant"
+	"github.com/sagernet/srsc/convertor"
+	"github.com/sagernet/srsc/metrics"
+	"github.com/sagernet/srsc/option"
+
+	"golang.org/x/sync/singleflight"
+)
+
+var _ adapter.Endpoint = (*MergeEndpoint)(nil)
+
+type MergeEndpoint struct {
+	ctx             context.Context
+	logger          logger.ContextLogger
+	cache           adapter.Cache
+	metrics         *metrics.Registry
+	index           int
+	path            string
+	sources         []*ruleSource
+	sourceTypes     string
+	excludes        []*ruleSource
+	targetConvertor adapter.Convertor
+	targetOptions   option.TargetConvertOptions
+	fetchGroup      singleflight.Group
+}
+
+func NewMergeEndpoint(ctx context.Context, logger logger.ContextLogger, index int, path string, options option.MergeEndpoint) (*MergeEndpoint, error) {
+	if len(options.Sources) == 0 {
+		return nil, E.New("missing sources")
+	}
+	ep := &MergeEndpoint{
+		ctx:           ctx,
+		logger:        logger,
+		cache:         service.FromContext[adapter.Cache](ctx),
+		metrics:       service.FromContext[*metrics.Registry](ctx),
+		index:         index,
+		path:          path,
+		targetOptions: options.TargetConvertOptions,
+	}
+	sources, err := newRuleSources(ctx, path, "source", options.Sources)
+	if err != nil {
+		return nil, err
+	}
+	ep.sources = sources
+	var sourceTypes []string
+	for _, sourceOptions := range options.Sources {
+		if !common.Contains(sourceTypes, sourceOptions.SourceType) {
+			sourceTypes = append(sourceTypes, sourceOptions.SourceType)
+		}
+	}
+	ep.sourceTypes = strings.Join(sourceTypes, ",")
+	excludes, err := newRuleSources(ctx, path, "exclude", options.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	ep.excludes = excludes
+	targetConvertor, loaded := convertor.Convertors[options.TargetConvertOptions.TargetType]
+	if !loaded {
+		return nil, E.New("unknown target type: ", options.TargetConvertOptions.TargetType)
+	}
+	ep.targetConvertor = targetConvertor
+	return ep, nil
+}
+
+func (m *MergeEndpoint) Start() error {
+	return nil
+}
+
+func (m *MergeEndpoint) Close() error {
+	return nil
+}
+
+// Refresh purges cached content of the endpoint and its sources, content will be fetched again on the next request.
+func (m *MergeEndpoint) Refresh() (int, error) {
+	purged, err := adapter.PurgeBinary(m.cache, F.ToString("merge.", m.index, "."))
+	if err != nil {
+		return purged, E.Cause(err, "purge cache")
+	}
+	return purged, nil
+}
+
+func (m *MergeEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	err := m.serveHTTP0(w, r)
+	if err != nil {
+		m.logger.Error("handle ", r.RemoteAddr, " - ", r.Header.Get("User-Agent"), " \"", r.Method, " ", r.URL.Path, " ", r.Proto, "\": ", err)
+	} else {
+		m.logger.Debug("accepted ", r.RemoteAddr, " - ", r.Header.Get("User-Agent"), " \"", r.Method, " ", r.URL.Path, " ", r.Proto, "\"")
+	}
+}
+
+func (m *MergeEndpoint) serveHTTP0(w http.ResponseWriter, r *http.Request) error {
+	metadata := C.DetectMetadata(r.UserAgent())
+	urlParams := routeParams(r)
+	sourcePaths, err := ruleSourcePaths(m.sources, "source", urlParams)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return err
+	}
+	excludePaths, err := ruleSourcePaths(m.excludes, "exclude", urlParams)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return err
+	}
+	if convertor.Variant(adapter.ConvertOptions{Options: option.ConvertOptions{TargetConvertOptions: m.targetOptions}, Metadata: metadata}) != "" {
+		w.Header().Add("Vary", "User-Agent")
+	}
+	cacheKey := m.cacheKey(sourcePaths, excludePaths, metadata)
+	result, err, _ := m.fetchGroup.Do(cacheKey, func() (any, error) {
+		return m.fetch(sourcePaths, excludePaths, cacheKey, metadata)
+	})
+	if err != nil {
+		writeError(w, err)
+		return err
+	}
+	contentType := m.targetConvertor.ContentType(adapter.ConvertOptions{
+		Options:  option.ConvertOptions{TargetConvertOptions: m.targetOptions},
+		Metadata: metadata,
+	})
+	fetched := result.(*fetchResult)
+	if fetched.staleErr != nil {
+		m.logger.Warn("serve stale content for endpoint ", m.path, ": ", fetched.staleErr)
+		metrics.ObserveStale(m.cache)
+		return writeStale(w, r, fetched.binary, contentType)
+	}
+	return writeCache(w, r, fetched.binary, contentType)
+}
+
+// CachedBinary returns the cache key and the cached content of the endpoint for urlParams,
+// content will be fetched first if not cached.
+func (m *MergeEndpoint) CachedBinary(urlParams map[string]string, metadata C.Metadata) (string, *adapter.SavedBinary, error) {
+	sourcePaths, err := ruleSourcePaths(m.sources, "source", urlParams)
+	if err != nil {
+		return "", nil, &statusError{http.StatusBadRequest, err}
+	}
+	excludePaths, err := ruleSourcePaths(m.excludes, "exclude", urlParams)
+	if err != nil {
+		return "", nil, &statusError{http.StatusBadRequest, err}
+	}
+	cacheKey := m.cacheKey(sourcePaths, excludePaths, metadata)
+	cachedBinary, err := m.cache.LoadBinary(cacheKey)
+	metrics.ObserveLoad(m.cache, cachedBinary, err)
+	if err != nil && !os.IsNotExist(err) {
+		return "", nil, E.Cause(err, "load cache binary")
+	}
+	if cachedBinary == nil {
+		result, err, _ := m.fetchGroup.Do(cacheKey, func() (any, error) {
+			return m.fetch(sourcePaths, excludePaths, cacheKey, metadata)
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		cachedBinary = result.(*fetchResult).binary
+	}
+	return cacheKey, cachedBinary, nil
+}
+
+func (m *MergeEndpoint) cacheKey(sourcePaths []string, excludePaths []string, metadata C.Metadata) string {
+	cacheKey := F.ToString("merge.", m.index, ".", strings.Join(sourcePaths, ","))
+	if len(excludePaths) > 0 {
+		cacheKey += "-" + strings.Join(excludePaths, ",")
+	}
+	if variant := convertor.Variant(adapter.ConvertOptions{Options: option.ConvertOptions{TargetConvertOptions: m.targetOptions}, Metadata: metadata}); variant != "" {
+		cacheKey += "@" + variant
+	}
+	return cacheKey
+}
+
+func (m *MergeEndpoint) fetch(sourcePaths []string, excludePaths []string, cacheKey string, metadata C.Metadata) (*fetchResult, error) {
+	cacheKeyPrefix := F.ToString("merge.", m.index)
+	sources, err := fetchRuleSources(m.cache, m.sources, "source", sourcePaths, cacheKeyPrefix, false)
+	if err != nil {
+		return nil, err
+	}
+	excludes, err := fetchRuleSources(m.cache, m.excludes, "exclude", excludePaths, cacheKeyPrefix, false)
+	if err != nil {
+		return nil, err
+	}
+	staleErr := E.Errors(sources.staleErr, excludes.staleErr)
+	sourceFingerprint := sources.fingerprint
+	if len(m.excludes) > 0 {
+		sourceFingerprint += "-" + excludes.fingerprint
+	}
+	cachedBinary, err := m.cache.LoadBinary(cacheKey)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, E.Cause(err, "load cache binary")
+	}
+	if cachedBinary != nil && cachedBinary.LastEtag == sourceFingerprint {
+		return &fetchResult{binary: cachedBinary, staleErr: staleErr}, nil
+	}
+	diagnostics := adapter.NewDiagnostics()
+	convertStartAt := time.Now()
+	rules, err := decodeRuleSources(m.ctx, m.sources, "source", sources.contents, m.targetOptions, metadata, diagnostics)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.excludes) > 0 {
+		var excludeRules []adapter.Rule
+		excludeRules, err = decodeRuleSources(m.ctx, m.excludes, "exclude", excludes.contents, m.targetOptions, metadata, nil)
+		if err != nil {
+			return nil, err
+		}
+		rules = adapter.ExcludeRules(rules, excludeRules)
+	}
+	convertOptions := adapter.ConvertOptions{
+		Options:     option.ConvertOptions{TargetConvertOptions: m.targetOptions},
+		Metadata:    metadata,
+		Diagnostics: diagnostics,
+	}
+	rules, err = convertor.OptimizeRules(m.ctx, m.logger, adapter.MergeRules(rules), convertOptions)
+	if err != nil {
+		return nil, err
+	}
+	binary, err := m.targetConvertor.To(m.ctx, rules, convertOptions)
+	if err != nil {
+		return nil, E.Cause(err, "encode target")
+	}
+	m.metrics.ObserveConversion(m.sourceTypes, m.targetConvertor.Type(), time.Since(convertStartAt))
+	savedBinary := &adapter.SavedBinary{
+		Content:      binary,
+		LastUpdated:  time.Now(),
+		LastEtag:     sourceFingerprint,
+		ContentEtag:  adapter.ContentEtag(binary),
+		LastModified: time.Now(),
+		Diagnostics:  diagnostics.Entries(),
+	}
+	if cachedBinary != nil && cachedBinary.ContentEtag == savedBinary.ContentEtag && !cachedBinary.LastModified.IsZero() {
+		savedBinary.LastModified = cachedBinary.LastModified
+	}
+	if compressible(convertOptions) {
+		savedBinary.Encoded, err = encodeContent(binary)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = m.cache.SaveBinary(cacheKey, savedBinary)
+	if err != nil {
+		return nil, E.Cause(err, "save cache binary")
+	}
+	return &fetchResult{binary: savedBinary, staleErr: staleErr}, nil
+}
@@ -0,0 +1,84 @@
+package endpoint
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/srsc/adapter"
+	C "github.com/sagernet/srsc/constant"
+	"github.com/sagernet/srsc/convertor"
+	"github.com/sagernet/srsc/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func clashTextSource() option.Resource {
+	var resource option.Resource
+	resource.Source = C.EndpointSourceLocal
+	resource.LocalOptions.Path = "test"
+	resource.SourceType = C.ConvertorTypeClashRuleProvider
+	resource.ClashOptions.SourceFormat = "text"
+	resource.ClashOptions.SourceBehavior = "classical"
+	return resource
+}
+
+func TestMergeEndpointOptimize(t *testing.T) {
+	t.Parallel()
+	sources := []string{
+		"DOMAIN,a.com\nDOMAIN-SUFFIX,b.com\nIP-CIDR,10.0.0.0/9\nPROCESS-NAME,curl\n",
+		"DOMAIN,A.com\nDOMAIN,c.b.com\nDOMAIN-SUFFIX,x.b.com\nIP-CIDR,10.128.0.0/9\n",
+	}
+	for _, testCase := range []struct {
+		name         string
+		optimize     bool
+		aggregate    bool
+		domain       []string
+		domainSuffix []string
+		ipCIDR       []string
+	}{
+		{
+			name:         "merged",
+			domain:       []string{"a.com", "A.com", "c.b.com"},
+			domainSuffix: []string{"b.com", "x.b.com"},
+			ipCIDR:       []string{"10.0.0.0/9", "10.128.0.0/9"},
+		},
+		{
+			name:         "optimize domain",
+			optimize:     true,
+			domain:       []string{"a.com"},
+			domainSuffix: []string{"b.com"},
+			ipCIDR:       []string{"10.0.0.0/9", "10.128.0.0/9"},
+		},
+		{
+			name:         "aggregate ip cidr",
+			aggregate:    true,
+			domain:       []string{"a.com", "A.com", "c.b.com"},
+			domainSuffix: []string{"b.com", "x.b.com"},
+			ipCIDR:       []string{"10.0.0.0/8"},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			var options option.MergeEndpoint
+			options.Sources = []option.Resource{clashTextSource(), clashTextSource()}
+			options.TargetConvertOptions.TargetType = C.ConvertorTypeRuleSetSource
+			options.TargetConvertOptions.OptimizeDomain = testCase.optimize
+			options.TargetConvertOptions.AggregateIPCIDR = testCase.aggregate
+			mergeEndpoint, err := NewMergeEndpoint(newTestContext(), logger.NOP(), 0, "/test", options)
+			require.NoError(t, err)
+			for index, content := range sources {
+				mergeEndpoint.sources[index].source = &testSource{content: []byte(content)}
+			}
+			_, cachedBinary, err := mergeEndpoint.CachedBinary(nil, C.Metadata{})
+			require.NoError(t, err)
+			rules, err := convertor.Convertors[C.ConvertorTypeRuleSetSource].From(context.Background(), cachedBinary.Content, adapter.ConvertOptions{})
+			require.NoError(t, err)
+			require.Len(t, rules, 2)
+			require.Equal(t, []string{"curl"}, []string(rules[1].DefaultOptions.ProcessName))
+			require.Equal(t, testCase.domain, []string(rules[0].DefaultOptions.Domain))
+			require.Equal(t, testCase.domainSuffix, []string(rules[0].DefaultOptions.DomainSuffix))
+			require.Equal(t, testCase.ipCIDR, []string(rules[0].DefaultOptions.IPCIDR))
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package endpoint
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/srsc/adapter"
+	C "github.com/sagernet/srsc/constant"
+	"github.com/sagernet/srsc/option"
+)
+
+// targetFormat returns the name of the target used by the `format` query parameter.
+func targetFormat(options option.TargetConvertOptions) string {
+	switch options.TargetType {
+	case C.ConvertorTypeRuleSetBinary:
+		return "srs"
+	case C.ConvertorTypeRuleSetSource:
+		return "json"
+	case C.ConvertorTypeClashRuleProvider:
+		switch options.ClashOptions.TargetFormat {
+		case "yaml", "mrs":
+			return options.ClashOptions.TargetFormat
+		default:
+			return "txt"
+		}
+	case C.ConvertorTypeSurgeRuleSet:
+		return "list"
+	default:
+		return options.TargetType
+	}
+}
+
+// clientFormats returns target formats supported by the client, ordered by preference.
+func clientFormats(platform C.Platform) []string {
+	switch platform {
+	case C.PlatformSingBox:
+		return []string{"srs", "json"}
+	case C.PlatformMihomo, C.PlatformClashVerge, C.PlatformClashMetaForAndroid:
+		return []string{"mrs", "yaml", "txt"}
+	case C.PlatformClash, C.PlatformStash:
+		return []string{"yaml", "txt"}
+	case C.PlatformSurge, C.PlatformLoon, C.PlatformShadowrocket:
+		return []string{"list"}
+	default:
+		return nil
+	}
+}
+
+// negotiateTarget selects the target for the request by the `format` query parameter,
+// the Accept header and the detected client in order, or the first target if none matches.
+func negotiateTarget(targets []*fileTarget, r *http.Request, metadata C.Metadata) (*fileTarget, error) {
+	if len(targets) == 1 {
+		return targets[0], nil
+	}
+	if format := r.URL.Query().Get("format"); format != "" {
+		for _, target := range targets {
+			if target.format == format {
+				return target, nil
+			}
+		}
+		return nil, &statusError{http.StatusNotAcceptable, E.New("unsupported format: ", format)}
+	}
+	if target := acceptedTarget(targets, r.Header.Get("Accept")); target != nil {
+		return target, nil
+	}
+	if target := clientTarget(targets, metadata); target != nil {
+		return target, nil
+	}
+	return targets[0], nil
+}
+
+// acceptedTarget returns the target whose content type is preferred by the Accept header, wildcards are ignored.
+func acceptedTarget(targets []*fileTarget, accept string) *fileTarget {
+	if accept == "" {
+		return nil
+	}
+	qualities := make(map[string]float64)
+	for _, item := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		if strings.HasSuffix(mediaType, "/*") {
+			continue
+		}
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if qualityString, loaded := strings.CutPrefix(strings.TrimSpace(param), "q="); loaded {
+				parsedQuality, err := strconv.ParseFloat(qualityString, 64)
+				if err == nil {
+					quality = parsedQuality
+				}
+			}
+		}
+		qualities[mediaType] = quality
+	}
+	var (
+		preferred        *fileTarget
+		preferredQuality float64
+	)
+	for _, target := range targets {
+		contentType := target.convertor.ContentType(adapter.ConvertOptions{Options: target.convertOptions})
+		quality, loaded := qualities[contentType]
+		if !loaded || quality <= preferredQuality {
+			continue
+		}
+		preferred = target
+		preferredQuality = quality
+	}
+	return preferred
+}
+
+// clientTarget returns the target preferred by the detected client, or nil if the client is unknown or unsupported.
+func clientTarget(targets []*fileTarget, metadata C.Metadata) *fileTarget {
+	for _, format := range clientFormats(metadata.Platform) {
+		for _, target := range targets {
+			if target.format == format {
+				return target
+			}
+		}
+	}
+	return nil
+}
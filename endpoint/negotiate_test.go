@@ -0,0 +1,83 @@
+package endpoint
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	C "github.com/sagernet/srsc/constant"
+	"github.com/sagernet/srsc/convertor"
+	"github.com/sagernet/srsc/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTarget(options option.TargetConvertOptions) *fileTarget {
+	return &fileTarget{
+		format:         targetFormat(options),
+		convertor:      convertor.Convertors[options.TargetType],
+		convertOptions: option.ConvertOptions{TargetConvertOptions: options},
+	}
+}
+
+func TestNegotiateTarget(t *testing.T) {
+	t.Parallel()
+	targets := []*fileTarget{
+		newTestTarget(option.TargetConvertOptions{TargetType: C.ConvertorTypeRuleSetBinary}),
+		newTestTarget(option.TargetConvertOptions{TargetType: C.ConvertorTypeClashRuleProvider, ClashOptions: option.ClashRuleProviderTargetOptions{TargetFormat: "mrs"}}),
+		newTestTarget(option.TargetConvertOptions{TargetType: C.ConvertorTypeSurgeRuleSet}),
+		newTestTarget(option.TargetConvertOptions{TargetType: C.ConvertorTypeClashRuleProvider, ClashOptions: option.ClashRuleProviderTargetOptions{TargetFormat: "yaml"}}),
+	}
+	for _, testCase := range []struct {
+		name      string
+		targets   []*fileTarget
+		query     string
+		accept    string
+		userAgent string
+		expected  string
+		status    int
+	}{
+		{name: "single target", targets: targets[2:3], query: "?format=srs", expected: "list"},
+		{name: "first target", expected: "srs"},
+		{name: "format", query: "?format=list", userAgent: "mihomo/1.18.6", expected: "list"},
+		{name: "format over accept", query: "?format=yaml", accept: "text/plain", expected: "yaml"},
+		{name: "unsupported format", query: "?format=txt", status: http.StatusNotAcceptable},
+		{name: "accept", accept: "application/x-yaml", expected: "yaml"},
+		{name: "accept quality", accept: "text/plain;q=0.5, application/x-yaml;q=0.9", expected: "yaml"},
+		{name: "accept first equal target", accept: "application/octet-stream", userAgent: "mihomo/1.18.6", expected: "srs"},
+		{name: "accept over client", accept: "text/plain", userAgent: "mihomo/1.18.6", expected: "list"},
+		{name: "accept wildcard ignored", accept: "*/*, text/*", userAgent: "Stash/2.4.6", expected: "yaml"},
+		{name: "accept unmatched", accept: "application/json", userAgent: "Surge iOS/2920", expected: "list"},
+		{name: "sing-box", userAgent: "SFA/1.10.0 (442; sing-box 1.10.0; language zh_CN)", expected: "srs"},
+		{name: "mihomo", userAgent: "mihomo/1.18.6", expected: "mrs"},
+		{name: "clash verge", userAgent: "clash-verge/v1.3.8", expected: "mrs"},
+		{name: "clash meta for android", userAgent: "ClashMetaForAndroid/2.8.0.Meta", expected: "mrs"},
+		{name: "quantumult x", userAgent: "Quantumult%20X/1.4.1", expected: "srs"},
+		{name: "clash", userAgent: "ClashX/1.118.0", expected: "yaml"},
+		{name: "surge", userAgent: "Surge Mac/2638", expected: "list"},
+		{name: "unknown client", userAgent: "curl/8.4.0", expected: "srs"},
+		{name: "unsupported client", targets: targets[:3], userAgent: "Stash/2.4.6", expected: "srs"},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			request := httptest.NewRequest("GET", "/rules"+testCase.query, nil)
+			if testCase.accept != "" {
+				request.Header.Set("Accept", testCase.accept)
+			}
+			request.Header.Set("User-Agent", testCase.userAgent)
+			negotiateTargets := testCase.targets
+			if negotiateTargets == nil {
+				negotiateTargets = targets
+			}
+			target, err := negotiateTarget(negotiateTargets, request, C.DetectMetadata(testCase.userAgent))
+			if testCase.status != 0 {
+				var negotiateErr *statusError
+				require.ErrorAs(t, err, &negotiateErr)
+				require.Equal(t, testCase.status, negotiateErr.statusCode)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testCase.expected, target.format)
+		})
+	}
+}
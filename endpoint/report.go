@@ -0,0 +1,91 @@
+package endpoint
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/srsc/adapter"
+	C "github.com/sagernet/srsc/constant"
+)
+
+var _ adapter.Endpoint = (*ReportEndpoint)(nil)
+
+// CachedEndpoint is an endpoint whose cached content can be inspected by other endpoints and refreshed.
+type CachedEndpoint interface {
+	adapter.Endpoint
+	CachedBinary(urlParams map[string]string, metadata C.Metadata) (string, *adapter.SavedBinary, error)
+	Refresh() (int, error)
+}
+
+type ReportEndpoint struct {
+	ctx      context.Context
+	logger   logger.ContextLogger
+	path     string
+	endpoint CachedEndpoint
+}
+
+type reportResponse struct {
+	Endpoint     string               `json:"endpoint"`
+	DroppedLines int                  `json:"dropped_lines"`
+	DroppedRules int                  `json:"dropped_rules"`
+	Diagnostics  []adapter.Diagnostic `json:"diagnostics"`
+}
+
+func NewReportEndpoint(ctx context.Context, logger logger.ContextLogger, path string, endpoint CachedEndpoint) *ReportEndpoint {
+	return &ReportEndpoint{
+		ctx:      ctx,
+		logger:   logger,
+		path:     path,
+		endpoint: endpoint,
+	}
+}
+
+func (e *ReportEndpoint) Start() error {
+	return nil
+}
+
+func (e *ReportEndpoint) Close() error {
+	return nil
+}
+
+func (e *ReportEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	err := e.serveHTTP0(w, r)
+	if err != nil {
+		e.logger.Error("handle ", r.RemoteAddr, " - ", r.Header.Get("User-Agent"), " \"", r.Method, " ", r.URL.Path, " ", r.Proto, "\": ", err)
+	} else {
+		e.logger.Debug("accepted ", r.RemoteAddr, " - ", r.Header.Get("User-Agent"), " \"", r.Method, " ", r.URL.Path, " ", r.Proto, "\"")
+	}
+}
+
+func (e *ReportEndpoint) serveHTTP0(w http.ResponseWriter, r *http.Request) error {
+	_, cachedBinary, err := e.endpoint.CachedBinary(routeParams(r), C.DetectMetadata(r.UserAgent()))
+	if err != nil {
+		writeError(w, err)
+		return err
+	}
+	response := reportResponse{
+		Endpoint:    e.path,
+		Diagnostics: cachedBinary.Diagnostics,
+	}
+	if response.Diagnostics == nil {
+		response.Diagnostics = []adapter.Diagnostic{}
+	}
+	response.DroppedLines, response.DroppedRules = adapter.DiagnosticCount(cachedBinary.Diagnostics)
+	buffer := new(bytes.Buffer)
+	err = json.NewEncoder(buffer).Encode(response)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return E.Cause(err, "encode response")
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_, err = w.Write(buffer.Bytes())
+	if err != nil {
+		return E.Cause(err, "write response")
+	}
+	return nil
+}
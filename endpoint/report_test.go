@@ -0,0 +1,49 @@
+package endpoint
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/srsc/adapter"
+	C "github.com/sagernet/srsc/constant"
+	"github.com/sagernet/srsc/option"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportEndpoint(t *testing.T) {
+	t.Parallel()
+	var options option.FileEndpoint
+	options.SourceType = C.ConvertorTypeClashRuleProvider
+	options.SourceConvertOptions.ClashOptions.SourceFormat = "text"
+	options.SourceConvertOptions.ClashOptions.SourceBehavior = "classical"
+	options.TargetType = C.ConvertorTypeHostsFile
+	fileEndpoint := newTestFileEndpoint(t, &testSource{content: []byte("DOMAIN,a.com\nUNKNOWN,b.com\nPROCESS-NAME,curl\n")}, options)
+	router := chi.NewRouter()
+	router.Get("/test", fileEndpoint.ServeHTTP)
+	router.Get("/test/report", NewReportEndpoint(fileEndpoint.ctx, logger.NOP(), "/test", fileEndpoint).ServeHTTP)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/test", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "0.0.0.0 a.com\n", recorder.Body.String())
+	require.Equal(t, "1", recorder.Header().Get("X-Srsc-Dropped-Lines"))
+	require.Equal(t, "1", recorder.Header().Get("X-Srsc-Dropped-Rules"))
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/test/report", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var response reportResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	require.Equal(t, "/test", response.Endpoint)
+	require.Equal(t, 1, response.DroppedLines)
+	require.Equal(t, 1, response.DroppedRules)
+	require.Equal(t, []adapter.Diagnostic{
+		{Type: adapter.DiagnosticTypeLine, Content: "UNKNOWN,b.com", Reason: "unsupported rule type: UNKNOWN"},
+		{Type: adapter.DiagnosticTypeRule, Content: `{"process_name": "curl"}`, Reason: "unsupported by hosts file"},
+	}, response.Diagnostics)
+}
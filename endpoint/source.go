@@ -0,0 +1,178 @@
+package endpoint
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"time"
+
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/srsc/adapter"
+	C "github.com/sagernet/srsc/constant"
+	"github.com/sagernet/srsc/convertor"
+	"github.com/sagernet/srsc/metrics"
+	"github.com/sagernet/srsc/option"
+	"github.com/sagernet/srsc/source"
+)
+
+type ruleSource struct {
+	source        adapter.Source
+	convertor     adapter.Convertor
+	sourceOptions option.SourceConvertOptions
+	staleIfError  bool
+	maxStale      time.Duration
+}
+
+func newRuleSources(ctx context.Context, path string, name string, options []option.Resource) ([]*ruleSource, error) {
+	var ruleSources []*ruleSource
+	for sourceIndex, sourceOptions := range options {
+		if sourceOptions.RefreshInterval > 0 {
+			return nil, E.New(name, "[", sourceIndex, "]: refresh_interval is not supported")
+		}
+		ruleSourceSource, err := source.New(ctx, sourceOptions.SourceOptions)
+		if err != nil {
+			return nil, E.Cause(err, "create ", name, "[", sourceIndex, "]")
+		}
+		sourceConvertor, loaded := convertor.Convertors[sourceOptions.SourceType]
+		if !loaded {
+			return nil, E.New(name, "[", sourceIndex, "]: unknown source type: ", sourceOptions.SourceType)
+		}
+		ruleSources = append(ruleSources, &ruleSource{
+			source:        metrics.NewSource(ctx, F.ToString(path, " ", name, "[", sourceIndex, "]"), ruleSourceSource),
+			convertor:     sourceConvertor,
+			sourceOptions: sourceOptions.SourceConvertOptions,
+			staleIfError:  sourceOptions.StaleIfError,
+			maxStale:      sourceOptions.MaxStale.Build(),
+		})
+	}
+	return ruleSources, nil
+}
+
+type ruleSourcesResult struct {
+	contents    [][]byte
+	fingerprint string
+	staleErr    error
+}
+
+func ruleSourcePaths(ruleSources []*ruleSource, name string, urlParams map[string]string) ([]string, error) {
+	sourcePaths := make([]string, 0, len(ruleSources))
+	for sourceIndex, ruleSource := range ruleSources {
+		sourcePath, err := ruleSource.source.Path(urlParams)
+		if err != nil {
+			return nil, E.Cause(err, "evaluate ", name, " path[", sourceIndex, "]")
+		}
+		sourcePaths = append(sourcePaths, sourcePath)
+	}
+	return sourcePaths, nil
+}
+
+// fetchRuleSources fetches ruleSources, sources are fetched again regardless of TTL if force is set.
+func fetchRuleSources(cache adapter.Cache, ruleSources []*ruleSource, name string, sourcePaths []string, cacheKeyPrefix string, force bool) (*ruleSourcesResult, error) {
+	var (
+		result      ruleSourcesResult
+		staleErrors []error
+	)
+	fingerprint := sha256.New()
+	for sourceIndex, ruleSource := range ruleSources {
+		cacheKey := F.ToString(cacheKeyPrefix, ".", name, ".", sourceIndex, ".", sourcePaths[sourceIndex])
+		fetched, err := ruleSource.fetch(cache, sourcePaths[sourceIndex], cacheKey, force)
+		if err != nil {
+			return nil, E.Cause(err, name, "[", sourceIndex, "]")
+		}
+		if fetched.staleErr != nil {
+			staleErrors = append(staleErrors, E.Cause(fetched.staleErr, name, "[", sourceIndex, "]"))
+		}
+		result.contents = append(result.contents, fetched.binary.Content)
+		fingerprint.Write([]byte(fetched.binary.ContentEtag))
+	}
+	result.fingerprint = hex.EncodeToString(fingerprint.Sum(nil))
+	result.staleErr = E.Errors(staleErrors...)
+	return &result, nil
+}
+
+func decodeRuleSources(ctx context.Context, ruleSources []*ruleSource, name string, contents [][]byte, targetOptions option.TargetConvertOptions, metadata C.Metadata, diagnostics *adapter.Diagnostics) ([]adapter.Rule, error) {
+	var rules []adapter.Rule
+	for sourceIndex, ruleSource := range ruleSources {
+		sourceRules, err := ruleSource.convertor.From(ctx, contents[sourceIndex], adapter.ConvertOptions{
+			Options: option.ConvertOptions{
+				SourceConvertOptions: ruleSource.sourceOptions,
+				TargetConvertOptions: targetOptions,
+			},
+			Metadata:    metadata,
+			Diagnostics: diagnostics,
+		})
+		if err != nil {
+			return nil, E.Cause(err, "decode ", name, "[", sourceIndex, "]")
+		}
+		rules = append(rules, sourceRules...)
+	}
+	return rules, nil
+}
+
+func (s *ruleSource) fetch(cache adapter.Cache, sourcePath string, cacheKey string, force bool) (*fetchResult, error) {
+	cachedBinary, err := cache.LoadBinary(cacheKey)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, E.Cause(err, "load cache binary")
+	}
+	lastUpdated := s.source.LastUpdated(sourcePath)
+	if cachedBinary != nil && !lastUpdated.IsZero() && cachedBinary.LastUpdated.Equal(lastUpdated) {
+		return &fetchResult{binary: cachedBinary}, nil
+	}
+	var fetchBody adapter.FetchRequestBody
+	if cachedBinary != nil {
+		fetchBody.ETag = cachedBinary.LastEtag
+		if !force {
+			fetchBody.LastUpdated = cachedBinary.LastUpdated
+		}
+	}
+	response, err := s.source.Fetch(sourcePath, fetchBody)
+	if err != nil {
+		err = E.Cause(err, "fetch source")
+		if s.staleAcceptable(cachedBinary) {
+			return &fetchResult{binary: cachedBinary, staleErr: err}, nil
+		}
+		return nil, &statusError{http.StatusBadGateway, err}
+	}
+	if response.NotModified {
+		if cachedBinary == nil {
+			return nil, &statusError{http.StatusBadGateway, E.New("fetch source: unexpected not modified response")}
+		}
+		if response.LastUpdated != cachedBinary.LastUpdated {
+			cachedBinary.LastUpdated = response.LastUpdated
+			err = cache.SaveBinary(cacheKey, cachedBinary)
+			if err != nil {
+				return nil, E.Cause(err, "save cache binary")
+			}
+		}
+		return &fetchResult{binary: cachedBinary}, nil
+	}
+	if len(response.Content) == 0 {
+		err = E.New("fetch source: empty content")
+		if s.staleAcceptable(cachedBinary) {
+			return &fetchResult{binary: cachedBinary, staleErr: err}, nil
+		}
+		return nil, &statusError{http.StatusBadGateway, err}
+	}
+	cachedBinary = &adapter.SavedBinary{
+		Content:      response.Content,
+		LastUpdated:  response.LastUpdated,
+		LastEtag:     response.ETag,
+		ContentEtag:  adapter.ContentEtag(response.Content),
+		LastModified: response.LastUpdated,
+	}
+	err = cache.SaveBinary(cacheKey, cachedBinary)
+	if err != nil {
+		return nil, E.Cause(err, "save cache binary")
+	}
+	return &fetchResult{binary: cachedBinary}, nil
+}
+
+func (s *ruleSource) staleAcceptable(cachedBinary *adapter.SavedBinary) bool {
+	if !s.staleIfError || cachedBinary == nil {
+		return false
+	}
+	return s.maxStale == 0 || time.Since(cachedBinary.LastUpdated) <= s.maxStale
+}
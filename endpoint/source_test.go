@@ -0,0 +1,89 @@
+package endpoint
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sagernet/srsc/adapter"
+	"github.com/sagernet/srsc/cache"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testSource serves content, or fails with err if set.
+type testSource struct {
+	content []byte
+	err     error
+	delay   time.Duration
+	fetches atomic.Int32
+}
+
+func (s *testSource) Path(urlParams map[string]string) (string, error) {
+	return "test", nil
+}
+
+func (s *testSource) LastUpdated(path string) time.Time {
+	return time.Time{}
+}
+
+func (s *testSource) Fetch(path string, requestBody adapter.FetchRequestBody) (*adapter.FetchResponseBody, error) {
+	s.fetches.Add(1)
+	time.Sleep(s.delay)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &adapter.FetchResponseBody{
+		Content:     s.content,
+		LastUpdated: time.Now(),
+	}, nil
+}
+
+func TestRuleSourceStale(t *testing.T) {
+	t.Parallel()
+	fetchErr := errors.New("connection refused")
+	for _, testCase := range []struct {
+		name         string
+		staleIfError bool
+		maxStale     time.Duration
+		cachedAge    time.Duration
+		noCache      bool
+		fetchErr     error
+		stale        bool
+	}{
+		{name: "disabled", fetchErr: fetchErr},
+		{name: "stale", staleIfError: true, cachedAge: 24 * time.Hour, fetchErr: fetchErr, stale: true},
+		{name: "within max stale", staleIfError: true, maxStale: time.Hour, cachedAge: time.Minute, fetchErr: fetchErr, stale: true},
+		{name: "exceeds max stale", staleIfError: true, maxStale: time.Hour, cachedAge: 2 * time.Hour, fetchErr: fetchErr},
+		{name: "no cache", staleIfError: true, noCache: true, fetchErr: fetchErr},
+		{name: "empty content", staleIfError: true, stale: true},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			memoryCache := cache.NewMemory(time.Hour)
+			if !testCase.noCache {
+				require.NoError(t, memoryCache.SaveBinary("test", &adapter.SavedBinary{
+					Content:     []byte("cached"),
+					LastUpdated: time.Now().Add(-testCase.cachedAge),
+				}))
+			}
+			ruleSource := &ruleSource{
+				source:       &testSource{err: testCase.fetchErr},
+				staleIfError: testCase.staleIfError,
+				maxStale:     testCase.maxStale,
+			}
+			result, err := ruleSource.fetch(memoryCache, "test", "test", false)
+			if !testCase.stale {
+				var fetchStatusErr *statusError
+				require.ErrorAs(t, err, &fetchStatusErr)
+				require.Equal(t, http.StatusBadGateway, fetchStatusErr.statusCode)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "cached", string(result.binary.Content))
+			require.Error(t, result.staleErr)
+		})
+	}
+}
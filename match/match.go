@@ -0,0 +1,251 @@
+package match
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net/netip"
+	"regexp"
+	"strings"
+
+	boxAdapter "github.com/sagernet/sing-box/adapter"
+	boxConstant "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/route/rule"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/srsc/adapter"
+	C "github.com/sagernet/srsc/constant"
+	"github.com/sagernet/srsc/convertor"
+)
+
+type Query struct {
+	Domain  string
+	IP      netip.Addr
+	Port    uint16
+	Network string
+}
+
+func (q Query) String() string {
+	var destination string
+	if q.IP.IsValid() {
+		destination = q.IP.String()
+	} else {
+		destination = q.Domain
+	}
+	if q.Port > 0 {
+		destination = M.ParseSocksaddrHostPort(destination, q.Port).String()
+	}
+	if q.Network != "" {
+		destination = q.Network + ":" + destination
+	}
+	return destination
+}
+
+type Result struct {
+	Matched    bool   `json:"matched"`
+	RuleIndex  int    `json:"rule_index"`
+	Item       string `json:"item,omitempty"`
+	Line       int    `json:"line,omitempty"`
+	SourceLine string `json:"source_line,omitempty"`
+}
+
+type Matcher struct {
+	rules         []adapter.Rule
+	headlessRules []boxAdapter.HeadlessRule
+	sourceLines   []string
+}
+
+// New creates a matcher for rules, resources in rules should be embedded first.
+//
+// If content is the text source of rules, matched items are looked up in it to report the original source line.
+func New(ctx context.Context, rules []adapter.Rule, content []byte) (*Matcher, error) {
+	matcher := &Matcher{
+		rules:         rules,
+		headlessRules: make([]boxAdapter.HeadlessRule, len(rules)),
+	}
+	for index, contentRule := range rules {
+		if !contentRule.Headlessable() {
+			continue
+		}
+		headlessRule, err := rule.NewHeadlessRule(ctx, contentRule.ToHeadless())
+		if err != nil {
+			return nil, E.Cause(err, "create rule[", index, "]")
+		}
+		matcher.headlessRules[index] = headlessRule
+	}
+	if len(content) > 0 {
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		scanner.Buffer(nil, len(content)+1)
+		for scanner.Scan() {
+			matcher.sourceLines = append(matcher.sourceLines, scanner.Text())
+		}
+	}
+	return matcher, nil
+}
+
+func (m *Matcher) Match(query Query) Result {
+	metadata := &boxAdapter.InboundContext{
+		Network: query.Network,
+		Domain:  query.Domain,
+	}
+	if query.IP.IsValid() {
+		metadata.Destination = M.SocksaddrFrom(query.IP, query.Port)
+		metadata.DestinationAddresses = []netip.Addr{query.IP}
+	} else {
+		metadata.Destination = M.Socksaddr{Fqdn: query.Domain, Port: query.Port}
+	}
+	for index, headlessRule := range m.headlessRules {
+		if headlessRule == nil || !headlessRule.Match(metadata) {
+			continue
+		}
+		result := Result{
+			Matched:   true,
+			RuleIndex: index,
+		}
+		if m.rules[index].Type == boxConstant.RuleTypeDefault {
+			itemType, itemValue := matchItem(m.rules[index].DefaultOptions, query)
+			if itemType != "" {
+				result.Item = itemType + "=" + itemValue
+				result.Line, result.SourceLine = m.findSourceLine(itemType, itemValue)
+			}
+		}
+		return result
+	}
+	return Result{RuleIndex: -1}
+}
+
+// sourceLineItemTypes maps rule types of Clash and Surge classical lines to item types.
+var sourceLineItemTypes = map[string]string{
+	"DOMAIN":         "domain",
+	"DOMAIN-SUFFIX":  "domain_suffix",
+	"DOMAIN-KEYWORD": "domain_keyword",
+	"DOMAIN-REGEX":   "domain_regex",
+	"IP-CIDR":        "ip_cidr",
+	"IP-CIDR6":       "ip_cidr",
+}
+
+// findSourceLine returns the number and content of the first source line with a token equal to the item,
+// lines of other rule types and comments are skipped.
+func (m *Matcher) findSourceLine(itemType string, itemValue string) (int, string) {
+	for index, line := range m.sourceLines {
+		if sourceLineContains(line, itemType, itemValue) {
+			return index + 1, line
+		}
+	}
+	return 0, ""
+}
+
+func sourceLineContains(line string, itemType string, itemValue string) bool {
+	trimmedLine := strings.TrimSpace(line)
+	if trimmedLine == "" || strings.HasPrefix(trimmedLine, "#") || strings.HasPrefix(trimmedLine, "!") || strings.HasPrefix(trimmedLine, "//") {
+		return false
+	}
+	tokens := strings.FieldsFunc(trimmedLine, func(r rune) bool {
+		switch r {
+		case ' ', '\t', ',', '"', '\'', '[', ']', '{', '}':
+			return true
+		default:
+			return false
+		}
+	})
+	for len(tokens) > 0 && tokens[0] == "-" {
+		tokens = tokens[1:]
+	}
+	if len(tokens) > 0 {
+		if lineItemType, loaded := sourceLineItemTypes[strings.ToUpper(tokens[0])]; loaded && lineItemType != itemType {
+			return false
+		}
+	}
+	for _, token := range tokens {
+		if strings.HasPrefix(token, "#") {
+			break
+		}
+		if sourceTokenEquals(token, itemType, itemValue) {
+			return true
+		}
+		adguardToken, _, _ := strings.Cut(strings.TrimPrefix(token, "@@"), "$")
+		adguardToken = strings.Trim(adguardToken, "|^")
+		if adguardToken != token && sourceTokenEquals(adguardToken, itemType, itemValue) {
+			return true
+		}
+	}
+	return false
+}
+
+func sourceTokenEquals(token string, itemType string, itemValue string) bool {
+	if strings.EqualFold(token, itemValue) {
+		return true
+	}
+	if itemType == "domain_suffix" && !strings.HasPrefix(itemValue, ".") {
+		return strings.EqualFold(token, "+."+itemValue) || strings.EqualFold(token, "."+itemValue)
+	}
+	return false
+}
+
+func matchItem(defaultRule adapter.DefaultRule, query Query) (string, string) {
+	domain := adapter.NormalizeDomain(query.Domain, false)
+	if domain != "" {
+		for _, ruleDomain := range defaultRule.Domain {
+			if domain == adapter.NormalizeDomain(ruleDomain, false) {
+				return "domain", ruleDomain
+			}
+		}
+		for _, domainSuffix := range defaultRule.DomainSuffix {
+			normalizedSuffix := adapter.NormalizeDomain(domainSuffix, true)
+			if strings.HasPrefix(normalizedSuffix, ".") {
+				if strings.HasSuffix(domain, normalizedSuffix) {
+					return "domain_suffix", domainSuffix
+				}
+			} else if domain == normalizedSuffix || strings.HasSuffix(domain, "."+normalizedSuffix) {
+				return "domain_suffix", domainSuffix
+			}
+		}
+		for _, domainKeyword := range defaultRule.DomainKeyword {
+			if strings.Contains(domain, strings.ToLower(domainKeyword)) {
+				return "domain_keyword", domainKeyword
+			}
+		}
+		for _, domainRegex := range defaultRule.DomainRegex {
+			regex, err := regexp.Compile(domainRegex)
+			if err == nil && regex.MatchString(domain) {
+				return "domain_regex", domainRegex
+			}
+		}
+	}
+	if query.IP.IsValid() {
+		for _, ipCIDR := range defaultRule.IPCIDR {
+			prefix, err := netip.ParsePrefix(ipCIDR)
+			if err != nil {
+				address, addrErr := netip.ParseAddr(ipCIDR)
+				if addrErr != nil {
+					continue
+				}
+				prefix = netip.PrefixFrom(address, address.BitLen())
+			}
+			if prefix.Contains(query.IP) {
+				return "ip_cidr", ipCIDR
+			}
+		}
+	}
+	return "", ""
+}
+
+// NewFromSource decodes content with the source convertor in options and creates a matcher.
+func NewFromSource(ctx context.Context, content []byte, options adapter.ConvertOptions) (*Matcher, error) {
+	sourceConvertor, loaded := convertor.Convertors[options.Options.SourceType]
+	if !loaded {
+		return nil, E.New("unknown source type: ", options.Options.SourceType)
+	}
+	rules, err := sourceConvertor.From(ctx, content, options)
+	if err != nil {
+		return nil, E.Cause(err, "decode source")
+	}
+	rules, err = adapter.EmbedResourceRules(ctx, rules)
+	if err != nil {
+		return nil, err
+	}
+	if options.Options.SourceType == C.ConvertorTypeRuleSetBinary || options.Options.SourceConvertOptions.ClashOptions.SourceFormat == "mrs" {
+		content = nil
+	}
+	return New(ctx, rules, content)
+}
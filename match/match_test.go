@@ -0,0 +1,129 @@
+package match
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/srsc/adapter"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindSourceLine(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name      string
+		lines     []string
+		itemType  string
+		itemValue string
+		expected  int
+	}{
+		{
+			name:      "classical skips substring and other rule types",
+			lines:     []string{"# a.com", "DOMAIN,ba.com", "DOMAIN-SUFFIX,a.com", "DOMAIN,a.com"},
+			itemType:  "domain",
+			itemValue: "a.com",
+			expected:  4,
+		},
+		{
+			name:      "yaml payload",
+			lines:     []string{"payload:", "  - 'ba.com'", "  - '+.a.com'"},
+			itemType:  "domain_suffix",
+			itemValue: "a.com",
+			expected:  3,
+		},
+		{
+			name:      "domain does not match suffix syntax",
+			lines:     []string{"+.a.com", ".a.com", "a.com"},
+			itemType:  "domain",
+			itemValue: "a.com",
+			expected:  3,
+		},
+		{
+			name:      "source json",
+			lines:     []string{`{"domain": ["ba.com"],`, `"domain_suffix": ["a.com", "b.com"]}`},
+			itemType:  "domain_suffix",
+			itemValue: "a.com",
+			expected:  2,
+		},
+		{
+			name:      "adguard",
+			lines:     []string{"! a.com", "||ba.com^", "||a.com^$important"},
+			itemType:  "domain_suffix",
+			itemValue: "a.com",
+			expected:  3,
+		},
+		{
+			name:      "hosts with inline comment",
+			lines:     []string{"0.0.0.0 b.com # a.com", "0.0.0.0 c.com A.com"},
+			itemType:  "domain",
+			itemValue: "a.com",
+			expected:  2,
+		},
+		{
+			name:      "ip cidr",
+			lines:     []string{"IP-CIDR,10.0.0.0/80", "IP-CIDR6,2001:db8::/32,no-resolve", "IP-CIDR,10.0.0.0/8,no-resolve"},
+			itemType:  "ip_cidr",
+			itemValue: "10.0.0.0/8",
+			expected:  3,
+		},
+		{
+			name:      "not found",
+			lines:     []string{"DOMAIN-KEYWORD,google"},
+			itemType:  "domain_keyword",
+			itemValue: "goo",
+			expected:  0,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			matcher := &Matcher{sourceLines: testCase.lines}
+			line, sourceLine := matcher.findSourceLine(testCase.itemType, testCase.itemValue)
+			require.Equal(t, testCase.expected, line)
+			if testCase.expected > 0 {
+				require.Equal(t, testCase.lines[testCase.expected-1], sourceLine)
+			}
+		})
+	}
+}
+
+func TestMatchItem(t *testing.T) {
+	t.Parallel()
+	defaultRule := adapter.DefaultRule{DefaultHeadlessRule: option.DefaultHeadlessRule{
+		Domain:        []string{"Example.COM", "bücher.example"},
+		DomainSuffix:  []string{".Sub.Example.org.", "Example.net"},
+		DomainKeyword: []string{"Google"},
+		IPCIDR:        []string{"10.0.0.0/8"},
+	}}
+	for _, testCase := range []struct {
+		domain   string
+		ip       string
+		expected string
+	}{
+		{domain: "example.com", expected: "domain=Example.COM"},
+		{domain: "EXAMPLE.com.", expected: "domain=Example.COM"},
+		{domain: "xn--bcher-kva.example", expected: "domain=bücher.example"},
+		{domain: "a.sub.example.org", expected: "domain_suffix=.Sub.Example.org."},
+		{domain: "sub.example.org"},
+		{domain: "Example.NET", expected: "domain_suffix=Example.net"},
+		{domain: "www.example.net", expected: "domain_suffix=Example.net"},
+		{domain: "www.GOOGLE.com", expected: "domain_keyword=Google"},
+		{ip: "10.1.2.3", expected: "ip_cidr=10.0.0.0/8"},
+		{domain: "example.org"},
+	} {
+		t.Run(testCase.domain+testCase.ip, func(t *testing.T) {
+			t.Parallel()
+			query := Query{Domain: testCase.domain}
+			if testCase.ip != "" {
+				query.IP = netip.MustParseAddr(testCase.ip)
+			}
+			itemType, itemValue := matchItem(defaultRule, query)
+			var item string
+			if itemType != "" {
+				item = itemType + "=" + itemValue
+			}
+			require.Equal(t, testCase.expected, item)
+		})
+	}
+}
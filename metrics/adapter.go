@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/sagernet/sing/service"
+	"github.com/sagernet/srsc/adapter"
+)
+
+var _ adapter.Cache = (*Cache)(nil)
+
+type Cache struct {
+	adapter.Cache
+	registry  *Registry
+	cacheType string
+}
+
+// NewCache wraps cache to record lookups with ObserveLoad and ObserveStale if a registry is registered in ctx.
+func NewCache(ctx context.Context, cacheType string, cache adapter.Cache) adapter.Cache {
+	registry := service.FromContext[*Registry](ctx)
+	if registry == nil {
+		return cache
+	}
+	return &Cache{
+		Cache:     cache,
+		registry:  registry,
+		cacheType: cacheType,
+	}
+}
+
+// ObserveLoad records the result of a cache lookup, cache should be created by NewCache.
+func ObserveLoad(cache adapter.Cache, binary *adapter.SavedBinary, err error) {
+	metricsCache, isMetricsCache := cache.(*Cache)
+	if !isMetricsCache {
+		return
+	}
+	if err != nil && !os.IsNotExist(err) {
+		metricsCache.registry.ObserveCache(metricsCache.cacheType, "error")
+	} else if binary == nil {
+		metricsCache.registry.ObserveCache(metricsCache.cacheType, "miss")
+	} else {
+		metricsCache.registry.ObserveCache(metricsCache.cacheType, "hit")
+	}
+}
+
+// ObserveStale records stale content served from cache, cache should be created by NewCache.
+func ObserveStale(cache adapter.Cache) {
+	if metricsCache, isMetricsCache := cache.(*Cache); isMetricsCache {
+		metricsCache.registry.ObserveCache(metricsCache.cacheType, "stale")
+	}
+}
+
+var _ adapter.Source = (*Source)(nil)
+
+type Source struct {
+	adapter.Source
+	registry *Registry
+	name     string
+}
+
+// NewSource wraps source to record fetches if a registry is registered in ctx.
+func NewSource(ctx context.Context, name string, source adapter.Source) adapter.Source {
+	registry := service.FromContext[*Registry](ctx)
+	if registry == nil {
+		return source
+	}
+	return &Source{
+		Source:   source,
+		registry: registry,
+		name:     name,
+	}
+}
+
+func (s *Source) Fetch(path string, requestBody adapter.FetchRequestBody) (*adapter.FetchResponseBody, error) {
+	startAt := time.Now()
+	response, err := s.Source.Fetch(path, requestBody)
+	if response != nil && response.Skipped {
+		return response, err
+	}
+	s.registry.ObserveFetch(s.name, time.Since(startAt), err)
+	return response, err
+}
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing/service"
+	"github.com/sagernet/srsc/adapter"
+	"github.com/sagernet/srsc/cache"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testSource struct {
+	adapter.Source
+	skipped bool
+}
+
+func (s *testSource) Fetch(path string, requestBody adapter.FetchRequestBody) (*adapter.FetchResponseBody, error) {
+	return &adapter.FetchResponseBody{NotModified: true, Skipped: s.skipped}, nil
+}
+
+func registryContext(registry *Registry) context.Context {
+	ctx := service.ContextWithDefaultRegistry(context.Background())
+	service.MustRegister[*Registry](ctx, registry)
+	return ctx
+}
+
+func registryLines(registry *Registry) []string {
+	var buffer bytes.Buffer
+	registry.WriteTo(&buffer)
+	return strings.Split(buffer.String(), "\n")
+}
+
+func TestSourceFetch(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name     string
+		skipped  bool
+		observed bool
+	}{
+		{"fetched", false, true},
+		{"skipped", true, false},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			registry := NewRegistry()
+			source := NewSource(registryContext(registry), "test", &testSource{skipped: testCase.skipped})
+			_, err := source.Fetch("", adapter.FetchRequestBody{})
+			require.NoError(t, err)
+			require.Equal(t, testCase.observed, slices.Contains(registryLines(registry), `srsc_source_fetch_duration_seconds_count{source="test"} 1`))
+		})
+	}
+}
+
+func TestObserveLoad(t *testing.T) {
+	t.Parallel()
+	registry := NewRegistry()
+	memoryCache := NewCache(registryContext(registry), "memory", cache.NewMemory(time.Minute))
+	require.NoError(t, memoryCache.SaveBinary("a", &adapter.SavedBinary{Content: []byte("a")}))
+	for _, tag := range []string{"a", "a", "b"} {
+		binary, err := memoryCache.LoadBinary(tag)
+		ObserveLoad(memoryCache, binary, err)
+	}
+	_, err := memoryCache.LoadBinary("a")
+	require.NoError(t, err)
+	ObserveStale(memoryCache)
+	lines := registryLines(registry)
+	require.Contains(t, lines, `srsc_cache_requests_total{cache="memory",result="hit"} 2`)
+	require.Contains(t, lines, `srsc_cache_requests_total{cache="memory",result="miss"} 1`)
+	require.Contains(t, lines, `srsc_cache_requests_total{cache="memory",result="stale"} 1`)
+}
@@ -0,0 +1,289 @@
+package metrics
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+var (
+	durationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30}
+	sizeBuckets     = []float64{1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20, 16 << 20, 64 << 20}
+)
+
+// Registry collects metrics of the server and writes them in the Prometheus text exposition format.
+//
+// Methods are safe to call on a nil Registry, observations are discarded in that case.
+type Registry struct {
+	access           sync.Mutex
+	families         []*family
+	requests         *family
+	requestDuration  *family
+	responseSize     *family
+	cacheRequests    *family
+	fetchDuration    *family
+	fetchErrors      *family
+	convertDuration  *family
+	asnLookups       *family
+	asnLookupSeconds *family
+}
+
+func NewRegistry() *Registry {
+	r := &Registry{}
+	r.requests = r.counter("srsc_http_requests_total", "Total number of HTTP requests by endpoint and status.", "endpoint", "status")
+	r.requestDuration = r.histogram("srsc_http_request_duration_seconds", "Duration of HTTP requests by endpoint and status.", durationBuckets, "endpoint", "status")
+	r.responseSize = r.histogram("srsc_http_response_size_bytes", "Size of HTTP response bodies by endpoint.", sizeBuckets, "endpoint")
+	r.cacheRequests = r.counter("srsc_cache_requests_total", "Total number of cache lookups by cache type and result.", "cache", "result")
+	r.fetchDuration = r.histogram("srsc_source_fetch_duration_seconds", "Duration of upstream fetches by source.", durationBuckets, "source")
+	r.fetchErrors = r.counter("srsc_source_fetch_errors_total", "Total number of failed upstream fetches by source.", "source")
+	r.convertDuration = r.histogram("srsc_conversion_duration_seconds", "Duration of conversions by source and target convertor.", durationBuckets, "source_type", "target_type")
+	r.asnLookups = r.counter("srsc_asn_lookups_total", "Total number of ASN prefix lookups by result.", "result")
+	r.asnLookupSeconds = r.histogram("srsc_asn_lookup_duration_seconds", "Duration of ASN prefix lookups.", durationBuckets)
+	return r
+}
+
+func (r *Registry) counter(name string, help string, labels ...string) *family {
+	metricFamily := &family{
+		name:   name,
+		help:   help,
+		kind:   "counter",
+		labels: labels,
+		series: make(map[string]*series),
+	}
+	r.families = append(r.families, metricFamily)
+	return metricFamily
+}
+
+func (r *Registry) histogram(name string, help string, buckets []float64, labels ...string) *family {
+	metricFamily := &family{
+		name:    name,
+		help:    help,
+		kind:    "histogram",
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+	r.families = append(r.families, metricFamily)
+	return metricFamily
+}
+
+func (r *Registry) ObserveRequest(endpoint string, status int, duration time.Duration, size int) {
+	if r == nil {
+		return
+	}
+	r.access.Lock()
+	defer r.access.Unlock()
+	statusString := strconv.Itoa(status)
+	r.requests.add(1, endpoint, statusString)
+	r.requestDuration.observe(duration.Seconds(), endpoint, statusString)
+	r.responseSize.observe(float64(size), endpoint)
+}
+
+func (r *Registry) ObserveCache(cacheType string, result string) {
+	if r == nil {
+		return
+	}
+	r.access.Lock()
+	defer r.access.Unlock()
+	r.cacheRequests.add(1, cacheType, result)
+}
+
+func (r *Registry) ObserveFetch(source string, duration time.Duration, err error) {
+	if r == nil {
+		return
+	}
+	r.access.Lock()
+	defer r.access.Unlock()
+	r.fetchDuration.observe(duration.Seconds(), source)
+	if err != nil {
+		r.fetchErrors.add(1, source)
+	}
+}
+
+func (r *Registry) ObserveConversion(sourceType string, targetType string, duration time.Duration) {
+	if r == nil {
+		return
+	}
+	r.access.Lock()
+	defer r.access.Unlock()
+	r.convertDuration.observe(duration.Seconds(), sourceType, targetType)
+}
+
+func (r *Registry) ObserveASNLookup(result string, duration time.Duration) {
+	if r == nil {
+		return
+	}
+	r.access.Lock()
+	defer r.access.Unlock()
+	r.asnLookups.add(1, result)
+	r.asnLookupSeconds.observe(duration.Seconds())
+}
+
+// Handler wraps handler to record requests of endpoint.
+func (r *Registry) Handler(endpoint string, handler http.Handler) http.HandlerFunc {
+	if r == nil {
+		return handler.ServeHTTP
+	}
+	return func(w http.ResponseWriter, request *http.Request) {
+		startAt := time.Now()
+		writer := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+		handler.ServeHTTP(writer, request)
+		r.ObserveRequest(endpoint, writer.status, time.Since(startAt), writer.size)
+	}
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, request *http.Request) {
+	var buffer bytes.Buffer
+	r.WriteTo(&buffer)
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(buffer.Bytes())
+}
+
+// WriteTo writes all metrics in the Prometheus text exposition format.
+func (r *Registry) WriteTo(buffer *bytes.Buffer) {
+	if r == nil {
+		return
+	}
+	r.access.Lock()
+	defer r.access.Unlock()
+	for _, metricFamily := range r.families {
+		metricFamily.writeTo(buffer)
+	}
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	size        int
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.status = statusCode
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *responseWriter) Write(content []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(content)
+	w.size += n
+	return n, err
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+type family struct {
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64
+	series  map[string]*series
+}
+
+type series struct {
+	labelValues  []string
+	value        float64
+	bucketCounts []uint64
+	count        uint64
+}
+
+func (f *family) load(labelValues []string) *series {
+	if len(labelValues) != len(f.labels) {
+		panic(E.New("metric ", f.name, ": expected ", len(f.labels), " labels, got ", len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\x00")
+	metricSeries, loaded := f.series[key]
+	if !loaded {
+		metricSeries = &series{
+			labelValues:  labelValues,
+			bucketCounts: make([]uint64, len(f.buckets)),
+		}
+		f.series[key] = metricSeries
+	}
+	return metricSeries
+}
+
+func (f *family) add(value float64, labelValues ...string) {
+	f.load(labelValues).value += value
+}
+
+func (f *family) observe(value float64, labelValues ...string) {
+	metricSeries := f.load(labelValues)
+	for index, bucket := range f.buckets {
+		if value <= bucket {
+			metricSeries.bucketCounts[index]++
+		}
+	}
+	metricSeries.value += value
+	metricSeries.count++
+}
+
+func (f *family) writeTo(buffer *bytes.Buffer) {
+	buffer.WriteString("# HELP " + f.name + " " + f.help + "\n")
+	buffer.WriteString("# TYPE " + f.name + " " + f.kind + "\n")
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		metricSeries := f.series[key]
+		labels := formatLabels(f.labels, metricSeries.labelValues)
+		if f.kind == "counter" {
+			buffer.WriteString(f.name + wrapLabels(labels) + " " + formatValue(metricSeries.value) + "\n")
+			continue
+		}
+		for index, bucket := range f.buckets {
+			buffer.WriteString(f.name + "_bucket" + wrapLabels(appendLabel(labels, "le", formatValue(bucket))) + " " + strconv.FormatUint(metricSeries.bucketCounts[index], 10) + "\n")
+		}
+		buffer.WriteString(f.name + "_bucket" + wrapLabels(appendLabel(labels, "le", "+Inf")) + " " + strconv.FormatUint(metricSeries.count, 10) + "\n")
+		buffer.WriteString(f.name + "_sum" + wrapLabels(labels) + " " + formatValue(metricSeries.value) + "\n")
+		buffer.WriteString(f.name + "_count" + wrapLabels(labels) + " " + strconv.FormatUint(metricSeries.count, 10) + "\n")
+	}
+}
+
+var labelValueReplacer = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
+
+func formatLabels(names []string, values []string) string {
+	var labels []string
+	for index, name := range names {
+		labels = append(labels, name+"=\""+labelValueReplacer.Replace(values[index])+"\"")
+	}
+	return strings.Join(labels, ",")
+}
+
+func appendLabel(labels string, name string, value string) string {
+	label := name + "=\"" + value + "\""
+	if labels == "" {
+		return label
+	}
+	return labels + "," + label
+}
+
+func wrapLabels(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func formatValue(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
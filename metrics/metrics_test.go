@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryWriteTo(t *testing.T) {
+	t.Parallel()
+	registry := NewRegistry()
+	registry.ObserveRequest("/a.srs", 200, 20*time.Millisecond, 2048)
+	registry.ObserveRequest("/a.srs", 200, 2*time.Second, 100)
+	registry.ObserveRequest("/a.srs", 404, time.Millisecond, 0)
+	registry.ObserveCache("memory", "hit")
+	registry.ObserveCache("memory", "hit")
+	registry.ObserveFetch("/a.srs source", time.Millisecond, nil)
+	registry.ObserveFetch("/a.srs source", time.Millisecond, errors.New("failed"))
+	registry.ObserveFetch("/\"b\"\\\n", time.Millisecond, errors.New("failed"))
+	registry.ObserveASNLookup("hit", 3*time.Millisecond)
+	var buffer bytes.Buffer
+	registry.WriteTo(&buffer)
+	lines := strings.Split(buffer.String(), "\n")
+	for _, testCase := range []struct {
+		name string
+		line string
+	}{
+		{"help", "# HELP srsc_http_requests_total Total number of HTTP requests by endpoint and status."},
+		{"type counter", "# TYPE srsc_http_requests_total counter"},
+		{"type histogram", "# TYPE srsc_http_request_duration_seconds histogram"},
+		{"counter", `srsc_http_requests_total{endpoint="/a.srs",status="200"} 2`},
+		{"counter by label", `srsc_http_requests_total{endpoint="/a.srs",status="404"} 1`},
+		{"bucket", `srsc_http_request_duration_seconds_bucket{endpoint="/a.srs",status="200",le="0.025"} 1`},
+		{"cumulative bucket", `srsc_http_request_duration_seconds_bucket{endpoint="/a.srs",status="200",le="2.5"} 2`},
+		{"inf bucket", `srsc_http_request_duration_seconds_bucket{endpoint="/a.srs",status="200",le="+Inf"} 2`},
+		{"sum", `srsc_http_request_duration_seconds_sum{endpoint="/a.srs",status="200"} 2.02`},
+		{"count", `srsc_http_request_duration_seconds_count{endpoint="/a.srs",status="200"} 2`},
+		{"size bucket", `srsc_http_response_size_bytes_bucket{endpoint="/a.srs",le="4096"} 3`},
+		{"cache", `srsc_cache_requests_total{cache="memory",result="hit"} 2`},
+		{"fetch errors", `srsc_source_fetch_errors_total{source="/a.srs source"} 1`},
+		{"escaped label", `srsc_source_fetch_errors_total{source="/\"b\"\\\n"} 1`},
+		{"no labels", `srsc_asn_lookup_duration_seconds_count 1`},
+		{"empty family", "# TYPE srsc_conversion_duration_seconds histogram"},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			require.Contains(t, lines, testCase.line)
+		})
+	}
+}
+
+func TestRegistryNil(t *testing.T) {
+	t.Parallel()
+	var registry *Registry
+	registry.ObserveRequest("/a.srs", 200, time.Millisecond, 0)
+	registry.ObserveCache("memory", "hit")
+	var buffer bytes.Buffer
+	registry.WriteTo(&buffer)
+	require.Zero(t, buffer.Len())
+}
@@ -0,0 +1,9 @@
+package option
+
+import "github.com/sagernet/sing/common/json/badoption"
+
+type AdminOptions struct {
+	Enabled bool                       `json:"enabled,omitempty"`
+	Path    string                     `json:"path,omitempty"`
+	Tokens  badoption.Listable[string] `json:"tokens,omitempty"`
+}
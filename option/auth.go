@@ -0,0 +1,13 @@
+package option
+
+import (
+	"github.com/sagernet/sing/common/auth"
+	"github.com/sagernet/sing/common/json/badoption"
+)
+
+type AuthOptions struct {
+	Tokens         badoption.Listable[string]               `json:"tokens,omitempty"`
+	QueryParameter string                                   `json:"query_parameter,omitempty"`
+	Users          []auth.User                              `json:"users,omitempty"`
+	AllowedIPs     badoption.Listable[badoption.Prefixable] `json:"allowed_ips,omitempty"`
+}
@@ -14,6 +14,7 @@ import (
 type _CacheOptions struct {
 	Type         string            `json:"type,omitempty"`
 	RedisOptions RedisCacheOptions `json:"-"`
+	FileOptions  FileCacheOptions  `json:"-"`
 	Expiration   time.Duration     `json:"expiration,omitempty"`
 }
 
@@ -22,8 +23,12 @@ type CacheOptions _CacheOptions
 func (o CacheOptions) MarshalJSON() ([]byte, error) {
 	var v any
 	switch o.Type {
+	case C.CacheTypeMemory:
+		return json.Marshal((_CacheOptions)(o))
 	case C.CacheTypeRedis:
 		v = o.RedisOptions
+	case C.CacheTypeFile:
+		v = o.FileOptions
 	case "":
 		return nil, E.New("missing cache type")
 	default:
@@ -39,8 +44,12 @@ func (o *CacheOptions) UnmarshalJSON(bytes []byte) error {
 	}
 	var v any
 	switch o.Type {
+	case C.CacheTypeMemory, "":
+		return nil
 	case C.CacheTypeRedis:
 		v = &o.RedisOptions
+	case C.CacheTypeFile:
+		v = &o.FileOptions
 	default:
 		return E.New("unknown cache type: " + o.Type)
 	}
@@ -56,3 +65,7 @@ type RedisCacheOptions struct {
 	PoolSize int                        `json:"pool_size,omitempty"`
 	option.OutboundTLSOptionsContainer
 }
+
+type FileCacheOptions struct {
+	Path string `json:"path,omitempty"`
+}
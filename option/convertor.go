@@ -4,6 +4,7 @@ import (
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/json"
 	"github.com/sagernet/sing/common/json/badjson"
+	"github.com/sagernet/sing/common/json/badoption"
 	C "github.com/sagernet/srsc/constant"
 )
 
@@ -25,7 +26,7 @@ func (o *ConvertOptions) UnmarshalJSON(bytes []byte) error {
 }
 
 func (o *ConvertOptions) ConvertRequired() bool {
-	if o.SourceType != o.TargetType {
+	if o.SourceType != o.TargetType || o.AggregateIPCIDR || o.OptimizeDomain || o.FilterSystemItems || o.Strict {
 		return true
 	}
 	switch o.SourceType {
@@ -34,6 +35,8 @@ func (o *ConvertOptions) ConvertRequired() bool {
 			o.SourceConvertOptions.ClashOptions.SourceBehavior != o.TargetConvertOptions.ClashOptions.TargetBehavior
 	case C.ConvertorTypeSurgeRuleSet:
 		return o.SourceConvertOptions.SurgeOptions.SourceBehavior != o.TargetConvertOptions.SurgeOptions.TargetBehavior
+	case C.ConvertorTypeHostsFile:
+		return o.HostsOptions.SinkAddress != nil
 	}
 	return false
 }
@@ -50,7 +53,7 @@ type SourceConvertOptions _SourceConvertOptions
 func (o SourceConvertOptions) MarshalJSON() ([]byte, error) {
 	var v any
 	switch o.SourceType {
-	case C.ConvertorTypeRuleSetSource, C.ConvertorTypeRuleSetBinary:
+	case C.ConvertorTypeRuleSetSource, C.ConvertorTypeRuleSetBinary, C.ConvertorTypeHostsFile:
 	case C.ConvertorTypeAdGuardRuleSet:
 		v = o.AdGuardOptions
 	case C.ConvertorTypeClashRuleProvider:
@@ -75,7 +78,7 @@ func (o *SourceConvertOptions) UnmarshalJSON(bytes []byte) error {
 	}
 	var v any
 	switch o.SourceType {
-	case C.ConvertorTypeRuleSetSource, C.ConvertorTypeRuleSetBinary:
+	case C.ConvertorTypeRuleSetSource, C.ConvertorTypeRuleSetBinary, C.ConvertorTypeHostsFile:
 	case C.ConvertorTypeAdGuardRuleSet:
 		v = &o.AdGuardOptions
 	case C.ConvertorTypeClashRuleProvider:
@@ -94,9 +97,14 @@ func (o *SourceConvertOptions) UnmarshalJSON(bytes []byte) error {
 }
 
 type _TargetConvertOptions struct {
-	TargetType   string                         `json:"target_type,omitempty"`
-	ClashOptions ClashRuleProviderTargetOptions `json:"-"`
-	SurgeOptions SurgeRuleProviderTargetOptions `json:"-"`
+	TargetType        string                         `json:"target_type,omitempty"`
+	AggregateIPCIDR   bool                           `json:"aggregate_ip_cidr,omitempty"`
+	OptimizeDomain    bool                           `json:"optimize_domain,omitempty"`
+	FilterSystemItems bool                           `json:"filter_system_items,omitempty"`
+	Strict            bool                           `json:"strict,omitempty"`
+	ClashOptions      ClashRuleProviderTargetOptions `json:"-"`
+	SurgeOptions      SurgeRuleProviderTargetOptions `json:"-"`
+	HostsOptions      HostsFileTargetOptions         `json:"-"`
 }
 
 type TargetConvertOptions _TargetConvertOptions
@@ -109,6 +117,8 @@ func (o TargetConvertOptions) MarshalJSON() ([]byte, error) {
 		v = o.ClashOptions
 	case C.ConvertorTypeSurgeRuleSet:
 		v = o.SurgeOptions
+	case C.ConvertorTypeHostsFile:
+		v = o.HostsOptions
 	case "":
 		return nil, E.New("missing target type")
 	default:
@@ -132,6 +142,8 @@ func (o *TargetConvertOptions) UnmarshalJSON(bytes []byte) error {
 		v = &o.ClashOptions
 	case C.ConvertorTypeSurgeRuleSet:
 		v = &o.SurgeOptions
+	case C.ConvertorTypeHostsFile:
+		v = &o.HostsOptions
 	case "":
 		return E.New("missing target type")
 	default:
@@ -164,3 +176,7 @@ type SurgeRuleProviderSourceOptions struct {
 type SurgeRuleProviderTargetOptions struct {
 	TargetBehavior string `json:"target_behavior,omitempty"`
 }
+
+type HostsFileTargetOptions struct {
+	SinkAddress *badoption.Addr `json:"sink_address,omitempty"`
+}
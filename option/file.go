@@ -14,26 +14,64 @@ import (
 type _FileEndpoint struct {
 	SourceOptions
 	ConvertOptions
+	ExcludeOptions
+	TargetsOptions
 }
 
 type FileEndpoint _FileEndpoint
 
 func (e FileEndpoint) MarshalJSON() ([]byte, error) {
-	return badjson.MarshallObjects(e.SourceOptions, e.ConvertOptions)
+	if len(e.Targets) > 0 {
+		return badjson.MarshallObjects(e.SourceOptions, e.SourceConvertOptions, e.ExcludeOptions, e.TargetsOptions)
+	}
+	return badjson.MarshallObjects(e.SourceOptions, e.ConvertOptions, e.ExcludeOptions)
 }
 
 func (e *FileEndpoint) UnmarshalJSON(bytes []byte) error {
-	err := json.Unmarshal(bytes, &e.SourceOptions)
+	err := json.Unmarshal(bytes, &e.ExcludeOptions)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(bytes, &e.TargetsOptions)
+	if err != nil {
+		return err
+	}
+	var content badjson.JSONObject
+	err = content.UnmarshalJSON(bytes)
+	if err != nil {
+		return err
+	}
+	content.Remove("exclude")
+	content.Remove("targets")
+	bytes, err = content.MarshalJSON()
 	if err != nil {
 		return err
 	}
+	err = json.Unmarshal(bytes, &e.SourceOptions)
+	if err != nil {
+		return err
+	}
+	if len(e.Targets) > 0 {
+		return badjson.UnmarshallExcludedMulti(bytes, &e.SourceOptions, &e.SourceConvertOptions)
+	}
 	return badjson.UnmarshallExcludedMulti(bytes, &e.SourceOptions, &e.ConvertOptions)
 }
 
+type ExcludeOptions struct {
+	Exclude []Resource `json:"exclude,omitempty"`
+}
+
+type TargetsOptions struct {
+	Targets []TargetConvertOptions `json:"targets,omitempty"`
+}
+
 type _SourceOptions struct {
-	Source        string       `json:"source,omitempty"`
-	LocalOptions  LocalSource  `json:"-"`
-	RemoteOptions RemoteSource `json:"-"`
+	Source          string             `json:"source,omitempty"`
+	StaleIfError    bool               `json:"stale_if_error,omitempty"`
+	MaxStale        badoption.Duration `json:"max_stale,omitempty"`
+	RefreshInterval badoption.Duration `json:"refresh_interval,omitempty"`
+	LocalOptions    LocalSource        `json:"-"`
+	RemoteOptions   RemoteSource       `json:"-"`
 }
 
 type SourceOptions _SourceOptions
@@ -0,0 +1,5 @@
+package option
+
+type MatchEndpoint struct {
+	Endpoint string `json:"endpoint,omitempty"`
+}
@@ -0,0 +1,26 @@
+package option
+
+import (
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/json/badjson"
+)
+
+type _MergeEndpoint struct {
+	Sources              []Resource           `json:"sources,omitempty"`
+	TargetConvertOptions TargetConvertOptions `json:"-"`
+	ExcludeOptions
+}
+
+type MergeEndpoint _MergeEndpoint
+
+func (e MergeEndpoint) MarshalJSON() ([]byte, error) {
+	return badjson.MarshallObjects((_MergeEndpoint)(e), e.TargetConvertOptions)
+}
+
+func (e *MergeEndpoint) UnmarshalJSON(bytes []byte) error {
+	err := json.Unmarshal(bytes, (*_MergeEndpoint)(e))
+	if err != nil {
+		return err
+	}
+	return badjson.UnmarshallExcluded(bytes, (*_MergeEndpoint)(e), &e.TargetConvertOptions)
+}
@@ -0,0 +1,6 @@
+package option
+
+type MetricsOptions struct {
+	Enabled bool   `json:"enabled,omitempty"`
+	Path    string `json:"path,omitempty"`
+}
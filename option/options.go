@@ -19,8 +19,11 @@ type _Options struct {
 	Endpoints  *badjson.TypedMap[string, *Endpoint] `json:"endpoints,omitempty"`
 	Resources  *ResourceOptions                     `json:"resources,omitempty"`
 	option.InboundTLSOptionsContainer
-	Cache      *CacheOptions `json:"cache,omitempty"`
-	RawMessage []byte        `json:"-"`
+	Cache      *CacheOptions   `json:"cache,omitempty"`
+	Metrics    *MetricsOptions `json:"metrics,omitempty"`
+	Admin      *AdminOptions   `json:"admin,omitempty"`
+	Auth       *AuthOptions    `json:"auth,omitempty"`
+	RawMessage []byte          `json:"-"`
 }
 
 type Options _Options
@@ -37,8 +40,13 @@ func (o *Options) UnmarshalJSONContext(ctx context.Context, content []byte) erro
 }
 
 type _Endpoint struct {
-	Type        string       `json:"type,omitempty"`
-	FileOptions FileEndpoint `json:"-"`
+	Type          string                                `json:"type,omitempty"`
+	BuildParams   map[string]badoption.Listable[string] `json:"build_params,omitempty"`
+	Auth          *AuthOptions                          `json:"auth,omitempty"`
+	FileOptions   FileEndpoint                          `json:"-"`
+	MergeOptions  MergeEndpoint                         `json:"-"`
+	MatchOptions  MatchEndpoint                         `json:"-"`
+	ReportOptions ReportEndpoint                        `json:"-"`
 }
 
 type Endpoint _Endpoint
@@ -48,6 +56,12 @@ func (o Endpoint) MarshalJSON() ([]byte, error) {
 	switch o.Type {
 	case C.EndpointTypeFile:
 		v = o.FileOptions
+	case C.EndpointTypeMerge:
+		v = o.MergeOptions
+	case C.EndpointTypeMatch:
+		v = o.MatchOptions
+	case C.EndpointTypeReport:
+		v = o.ReportOptions
 	case "":
 		return nil, E.New("missing endpoint type")
 	default:
@@ -65,6 +79,12 @@ func (o *Endpoint) UnmarshalJSON(bytes []byte) error {
 	switch o.Type {
 	case C.EndpointTypeFile:
 		v = &o.FileOptions
+	case C.EndpointTypeMerge:
+		v = &o.MergeOptions
+	case C.EndpointTypeMatch:
+		v = &o.MatchOptions
+	case C.EndpointTypeReport:
+		v = &o.ReportOptions
 	default:
 		return E.New("unknown endpoint type: " + o.Type)
 	}
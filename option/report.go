@@ -0,0 +1,5 @@
+package option
+
+type ReportEndpoint struct {
+	Endpoint string `json:"endpoint,omitempty"`
+}
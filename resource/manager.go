@@ -3,37 +3,52 @@ package resource
 import (
 	"context"
 	"os"
+	"strings"
+	"time"
 
 	boxConstant "github.com/sagernet/sing-box/constant"
 	boxOption "github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/sing/contrab/freelru"
+	"github.com/sagernet/sing/contrab/maphash"
 	"github.com/sagernet/sing/service"
 	"github.com/sagernet/srsc/adapter"
 	C "github.com/sagernet/srsc/constant"
 	"github.com/sagernet/srsc/convertor"
+	"github.com/sagernet/srsc/metrics"
 	"github.com/sagernet/srsc/option"
 	"github.com/sagernet/srsc/source"
+
+	"golang.org/x/sync/singleflight"
 )
 
 var _ adapter.ResourceManager = (*Manager)(nil)
 
 type Manager struct {
-	ctx     context.Context
-	logger  logger.ContextLogger
-	cache   adapter.Cache
-	geoip   *Resource
-	geosite *Resource
-	ipasn   *Resource
+	ctx        context.Context
+	logger     logger.ContextLogger
+	cache      adapter.Cache
+	geoip      *Resource
+	geosite    *Resource
+	ipasn      *Resource
+	fetchGroup singleflight.Group
+	done       chan struct{}
 }
 
 type Resource struct {
 	adapter.Source
 	adapter.Convertor
 	option.SourceConvertOptions
+	cacheKeyPrefix  string
+	staleIfError    bool
+	maxStale        time.Duration
+	refreshInterval time.Duration
+	refreshPaths    freelru.Cache[string, string]
 }
 
-func NewResource(ctx context.Context, options *option.Resource) (*Resource, error) {
+func NewResource(ctx context.Context, name string, cacheKeyPrefix string, options *option.Resource) (*Resource, error) {
 	resSource, err := source.New(ctx, options.SourceOptions)
 	if err != nil {
 		return nil, err
@@ -42,11 +57,27 @@ func NewResource(ctx context.Context, options *option.Resource) (*Resource, erro
 	if !loaded {
 		return nil, E.New("unknown source type: ", options.SourceType)
 	}
-	return &Resource{
-		Source:               resSource,
+	resource := &Resource{
+		Source:               metrics.NewSource(ctx, "resource "+name, resSource),
 		Convertor:            resConvertor,
 		SourceConvertOptions: options.SourceConvertOptions,
-	}, nil
+		cacheKeyPrefix:       cacheKeyPrefix,
+		staleIfError:         options.StaleIfError,
+		maxStale:             options.MaxStale.Build(),
+		refreshInterval:      options.RefreshInterval.Build(),
+	}
+	if resource.refreshInterval > 0 {
+		resource.refreshPaths = common.Must1(freelru.NewSynced[string, string](C.RefreshPathCapacity, maphash.NewHasher[string]().Hash32))
+		resource.refreshPaths.SetLifetime(C.RefreshPathIdleTimeout)
+	}
+	return resource, nil
+}
+
+func (r *Resource) staleAcceptable(cachedBinary *adapter.SavedBinary) bool {
+	if !r.staleIfError || cachedBinary == nil {
+		return false
+	}
+	return r.maxStale == 0 || time.Since(cachedBinary.LastUpdated) <= r.maxStale
 }
 
 func NewManager(ctx context.Context, logger logger.ContextLogger, options option.ResourceOptions) (*Manager, error) {
@@ -54,23 +85,24 @@ func NewManager(ctx context.Context, logger logger.ContextLogger, options option
 		ctx:    ctx,
 		logger: logger,
 		cache:  service.FromContext[adapter.Cache](ctx),
+		done:   make(chan struct{}),
 	}
 	if options.GEOIP != nil {
-		geoip, err := NewResource(ctx, options.GEOIP)
+		geoip, err := NewResource(ctx, "GEOIP", "res.geoip.", options.GEOIP)
 		if err != nil {
 			return nil, E.Cause(err, "create resource for GEOIP")
 		}
 		m.geoip = geoip
 	}
 	if options.GEOSite != nil {
-		geosite, err := NewResource(ctx, options.GEOSite)
+		geosite, err := NewResource(ctx, "GEOSite", "res.geosite.", options.GEOSite)
 		if err != nil {
 			return nil, E.Cause(err, "create resource for GEOSite")
 		}
 		m.geosite = geosite
 	}
 	if options.IPASN != nil {
-		ipasn, err := NewResource(ctx, options.IPASN)
+		ipasn, err := NewResource(ctx, "IPASN", "res.ipasn.", options.IPASN)
 		if err != nil {
 			return nil, E.Cause(err, "create resource for IPASN")
 		}
@@ -79,6 +111,69 @@ func NewManager(ctx context.Context, logger logger.ContextLogger, options option
 	return m, nil
 }
 
+func (m *Manager) Start() error {
+	for _, r := range []*Resource{m.geoip, m.geosite, m.ipasn} {
+		if r != nil && r.refreshInterval > 0 {
+			go m.loopRefresh(r)
+		}
+	}
+	return nil
+}
+
+func (m *Manager) Close() error {
+	select {
+	case <-m.done:
+	default:
+		close(m.done)
+	}
+	return nil
+}
+
+func (m *Manager) loopRefresh(r *Resource) {
+	// pre-warm paths cached by previous runs, which are lost with memory cache
+	cacheKeys, err := m.cache.ListBinary(r.cacheKeyPrefix)
+	if err != nil {
+		m.logger.Error("list cached resources ", r.cacheKeyPrefix, ": ", err)
+	}
+	for _, cacheKey := range cacheKeys {
+		r.refreshPaths.Add(cacheKey, strings.TrimPrefix(cacheKey, r.cacheKeyPrefix))
+	}
+	m.refresh(r)
+	ticker := time.NewTicker(r.refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.refresh(r)
+		case <-m.done:
+			return
+		}
+	}
+}
+
+func (m *Manager) refresh(r *Resource) {
+	for _, cacheKey := range r.refreshPaths.Keys() {
+		cachePath, loaded := r.refreshPaths.Peek(cacheKey)
+		if !loaded {
+			continue
+		}
+		select {
+		case <-m.done:
+			return
+		default:
+		}
+		_, err, _ := m.fetchGroup.Do(cacheKey, func() (any, error) {
+			return m.fetch0(r, cachePath, cacheKey, true)
+		})
+		if err != nil {
+			m.logger.Error("refresh resource ", cacheKey, ": ", err)
+			r.refreshPaths.Remove(cacheKey)
+		} else {
+			m.logger.Debug("refreshed resource ", cacheKey)
+		}
+	}
+}
+
 func (m *Manager) GEOIPConfigured() bool {
 	return m.geoip != nil
 }
@@ -93,7 +188,7 @@ func (m *Manager) GEOIP(code string) (*boxOption.DefaultHeadlessRule, error) {
 	if err != nil {
 		return nil, E.Cause(err, "evaluate source path")
 	}
-	return m.fetch(m.geoip, cachePath, "res.geoip."+cachePath)
+	return m.fetch(m.geoip, cachePath, m.geoip.cacheKeyPrefix+cachePath)
 }
 
 func (m *Manager) GEOSiteConfigured() bool {
@@ -110,7 +205,7 @@ func (m *Manager) GEOSite(code string) (*boxOption.DefaultHeadlessRule, error) {
 	if err != nil {
 		return nil, E.Cause(err, "evaluate source path")
 	}
-	return m.fetch(m.geosite, cachePath, "res.geosite."+cachePath)
+	return m.fetch(m.geosite, cachePath, m.geosite.cacheKeyPrefix+cachePath)
 }
 
 func (m *Manager) IPASNConfigured() bool {
@@ -127,11 +222,27 @@ func (m *Manager) IPASN(asn string) (*boxOption.DefaultHeadlessRule, error) {
 	if err != nil {
 		return nil, E.Cause(err, "evaluate source path")
 	}
-	return m.fetch(m.ipasn, cachePath, "res.ipasn."+cachePath)
+	return m.fetch(m.ipasn, cachePath, m.ipasn.cacheKeyPrefix+cachePath)
 }
 
 func (m *Manager) fetch(r *Resource, cachePath string, cacheKey string) (*boxOption.DefaultHeadlessRule, error) {
+	rule, err, _ := m.fetchGroup.Do(cacheKey, func() (any, error) {
+		return m.fetch0(r, cachePath, cacheKey, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if r.refreshInterval > 0 {
+		r.refreshPaths.Add(cacheKey, cachePath)
+	}
+	return rule.(*boxOption.DefaultHeadlessRule), nil
+}
+
+func (m *Manager) fetch0(r *Resource, cachePath string, cacheKey string, force bool) (*boxOption.DefaultHeadlessRule, error) {
 	cachedBinary, err := m.cache.LoadBinary(cacheKey)
+	if !force {
+		metrics.ObserveLoad(m.cache, cachedBinary, err)
+	}
 	if err != nil && !os.IsNotExist(err) {
 		return nil, E.Cause(err, "load cache binary")
 	}
@@ -142,11 +253,18 @@ func (m *Manager) fetch(r *Resource, cachePath string, cacheKey string) (*boxOpt
 	var fetchBody adapter.FetchRequestBody
 	if cachedBinary != nil {
 		fetchBody.ETag = cachedBinary.LastEtag
-		fetchBody.LastUpdated = cachedBinary.LastUpdated
+		if !force {
+			fetchBody.LastUpdated = cachedBinary.LastUpdated
+		}
 	}
 	response, err := r.Fetch(cachePath, fetchBody)
 	if err != nil {
-		return nil, E.Cause(err, "fetch source")
+		err = E.Cause(err, "fetch source")
+		if r.staleAcceptable(cachedBinary) {
+			m.logger.Warn("use stale resource ", cacheKey, ": ", err)
+			return m.loadCache(cachedBinary)
+		}
+		return nil, err
 	}
 	if response.NotModified {
 		if cachedBinary == nil {
@@ -162,7 +280,12 @@ func (m *Manager) fetch(r *Resource, cachePath string, cacheKey string) (*boxOpt
 		return m.loadCache(cachedBinary)
 	}
 	if len(response.Content) == 0 {
-		return nil, E.Cause(err, "fetch source: empty content")
+		err = E.New("fetch source: empty content")
+		if r.staleAcceptable(cachedBinary) {
+			m.logger.Warn("use stale resource ", cacheKey, ": ", err)
+			return m.loadCache(cachedBinary)
+		}
+		return nil, err
 	}
 	var rules []adapter.Rule
 	rules, err = r.From(m.ctx, response.Content, adapter.ConvertOptions{
@@ -171,7 +294,12 @@ func (m *Manager) fetch(r *Resource, cachePath string, cacheKey string) (*boxOpt
 		},
 	})
 	if err != nil {
-		return nil, E.Cause(err, "decode source")
+		err = E.Cause(err, "decode source")
+		if r.staleAcceptable(cachedBinary) {
+			m.logger.Warn("use stale resource ", cacheKey, ": ", err)
+			return m.loadCache(cachedBinary)
+		}
+		return nil, err
 	}
 	if len(rules) != 1 {
 		return nil, E.New("unexpected resource rule count: ", len(rules))
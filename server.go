@@ -19,9 +19,11 @@ import (
 	aTLS "github.com/sagernet/sing/common/tls"
 	"github.com/sagernet/sing/service"
 	"github.com/sagernet/srsc/adapter"
+	"github.com/sagernet/srsc/admin"
 	"github.com/sagernet/srsc/cache"
 	C "github.com/sagernet/srsc/constant"
 	"github.com/sagernet/srsc/endpoint"
+	"github.com/sagernet/srsc/metrics"
 	"github.com/sagernet/srsc/option"
 	"github.com/sagernet/srsc/resource"
 
@@ -37,7 +39,11 @@ type Server struct {
 	listener   *listener.Listener
 	tlsConfig  tls.ServerConfig
 	httpServer *http.Server
+	router     *chi.Mux
+	routes     []route
 	cache      adapter.Cache
+	resources  *resource.Manager
+	endpoints  []adapter.Endpoint
 }
 
 type Options struct {
@@ -65,10 +71,20 @@ func NewServer(options Options) (*Server, error) {
 		options.Logger = logFactory.Logger()
 		// TODO: improve log
 	}
-	serviceCache, err := cache.New(ctx, common.PtrValueOrDefault(options.Cache))
+	var metricsRegistry *metrics.Registry
+	if options.Metrics != nil && options.Metrics.Enabled {
+		metricsRegistry = metrics.NewRegistry()
+		service.MustRegister[*metrics.Registry](ctx, metricsRegistry)
+	}
+	cacheOptions := common.PtrValueOrDefault(options.Cache)
+	serviceCache, err := cache.New(ctx, cacheOptions)
 	if err != nil {
 		return nil, E.Cause(err, "create cache")
 	}
+	if cacheOptions.Type == "" {
+		cacheOptions.Type = C.CacheTypeMemory
+	}
+	serviceCache = metrics.NewCache(ctx, cacheOptions.Type, serviceCache)
 	service.MustRegister[adapter.Cache](ctx, serviceCache)
 	resourceManage, err := resource.NewManager(ctx, options.Logger, common.PtrValueOrDefault(options.Resources))
 	if err != nil {
@@ -92,25 +108,95 @@ func NewServer(options Options) (*Server, error) {
 		httpServer: &http.Server{
 			Handler: chiRouter,
 		},
-		cache: serviceCache,
+		router:    chiRouter,
+		cache:     serviceCache,
+		resources: resourceManage,
 	}
 	if options.Endpoints == nil || options.Endpoints.Size() == 0 {
 		return nil, E.New("missing endpoints")
 	}
+	fileEndpoints := make(map[string]*endpoint.FileEndpoint)
+	cachedEndpoints := make(map[string]endpoint.CachedEndpoint)
 	for index, entry := range options.Endpoints.Entries() {
 		if !strings.HasPrefix(entry.Key, "/") {
 			return nil, E.New("routing pattern must begin with '/': [", index, "]: ", entry.Key)
 		}
+		authOptions := entry.Value.Auth
+		if authOptions == nil {
+			authOptions = options.Auth
+		}
+		authenticator := endpoint.NewAuthenticator(options.Logger, authOptions)
 		switch entry.Value.Type {
 		case C.EndpointTypeFile:
-			handler, err := endpoint.NewFileEndpoint(ctx, options.Logger, index, entry.Value.FileOptions)
+			handler, err := endpoint.NewFileEndpoint(ctx, options.Logger, index, entry.Key, entry.Value.FileOptions)
 			if err != nil {
 				return nil, err
 			}
-			chiRouter.Get(entry.Key, handler.ServeHTTP)
+			chiRouter.Get(entry.Key, metricsRegistry.Handler(entry.Key, authenticator.Handler(handler)))
+			s.endpoints = append(s.endpoints, handler)
+			fileEndpoints[entry.Key] = handler
+			cachedEndpoints[entry.Key] = handler
+			s.routes = append(s.routes, route{
+				pattern:     entry.Key,
+				buildParams: entry.Value.BuildParams,
+				formats:     handler.TargetFormats(),
+			})
+			continue
+		case C.EndpointTypeMerge:
+			handler, err := endpoint.NewMergeEndpoint(ctx, options.Logger, index, entry.Key, entry.Value.MergeOptions)
+			if err != nil {
+				return nil, E.Cause(err, "create merge endpoint[", index, "]")
+			}
+			chiRouter.Get(entry.Key, metricsRegistry.Handler(entry.Key, authenticator.Handler(handler)))
+			s.endpoints = append(s.endpoints, handler)
+			cachedEndpoints[entry.Key] = handler
+		case C.EndpointTypeMatch:
+			fileEndpoint, loaded := fileEndpoints[entry.Value.MatchOptions.Endpoint]
+			if !loaded {
+				return nil, E.New("create match endpoint[", index, "]: file endpoint not found or not defined before: ", entry.Value.MatchOptions.Endpoint)
+			}
+			handler := endpoint.NewMatchEndpoint(ctx, options.Logger, fileEndpoint)
+			chiRouter.Get(entry.Key, metricsRegistry.Handler(entry.Key, authenticator.Handler(handler)))
+			s.endpoints = append(s.endpoints, handler)
+			// match endpoints are for debugging and not built
+			continue
+		case C.EndpointTypeReport:
+			cachedEndpoint, loaded := cachedEndpoints[entry.Value.ReportOptions.Endpoint]
+			if !loaded {
+				return nil, E.New("create report endpoint[", index, "]: file or merge endpoint not found or not defined before: ", entry.Value.ReportOptions.Endpoint)
+			}
+			handler := endpoint.NewReportEndpoint(ctx, options.Logger, entry.Value.ReportOptions.Endpoint, cachedEndpoint)
+			chiRouter.Get(entry.Key, metricsRegistry.Handler(entry.Key, authenticator.Handler(handler)))
+			s.endpoints = append(s.endpoints, handler)
 		default:
 			return nil, E.New("unknown endpoint type: " + entry.Value.Type)
 		}
+		s.routes = append(s.routes, route{
+			pattern:     entry.Key,
+			buildParams: entry.Value.BuildParams,
+		})
+	}
+	if options.Admin != nil && options.Admin.Enabled {
+		adminHandler, err := admin.NewHandler(ctx, options.Logger, common.PtrValueOrDefault(options.Admin), cachedEndpoints)
+		if err != nil {
+			return nil, E.Cause(err, "create admin API")
+		}
+		adminPath := options.Admin.Path
+		if adminPath == "" {
+			adminPath = "/admin"
+		} else if !strings.HasPrefix(adminPath, "/") {
+			return nil, E.New("admin path must begin with '/': ", adminPath)
+		}
+		chiRouter.Mount(adminPath, adminHandler)
+	}
+	if metricsRegistry != nil {
+		metricsPath := options.Metrics.Path
+		if metricsPath == "" {
+			metricsPath = "/metrics"
+		} else if !strings.HasPrefix(metricsPath, "/") {
+			return nil, E.New("metrics path must begin with '/': ", metricsPath)
+		}
+		chiRouter.Get(metricsPath, endpoint.NewAuthenticator(options.Logger, options.Auth).Handler(metricsRegistry).ServeHTTP)
 	}
 	if options.TLS != nil {
 		tlsConfig, err := tls.NewServer(ctx, options.Logger, common.PtrValueOrDefault(options.TLS))
@@ -129,8 +215,18 @@ func (s *Server) Start() error {
 			return E.Cause(err, "start cache")
 		}
 	}
+	err := s.resources.Start()
+	if err != nil {
+		return E.Cause(err, "start resource manager")
+	}
+	for _, handler := range s.endpoints {
+		err = handler.Start()
+		if err != nil {
+			return E.Cause(err, "start endpoint")
+		}
+	}
 	if s.tlsConfig != nil {
-		err := s.tlsConfig.Start()
+		err = s.tlsConfig.Start()
 		if err != nil {
 			return E.Cause(err, "create TLS config")
 		}
@@ -156,10 +252,20 @@ func (s *Server) Start() error {
 }
 
 func (s *Server) Close() error {
-	return common.Close(
-		common.PtrOrNil(s.httpServer),
-		common.PtrOrNil(s.listener),
-		s.tlsConfig,
-		s.cache,
+	var endpoints []any
+	for _, handler := range s.endpoints {
+		endpoints = append(endpoints, handler)
+	}
+	return E.Errors(
+		common.Close(
+			common.PtrOrNil(s.httpServer),
+			common.PtrOrNil(s.listener),
+			s.tlsConfig,
+		),
+		common.Close(endpoints...),
+		common.Close(
+			common.PtrOrNil(s.resources),
+			s.cache,
+		),
 	)
 }
@@ -165,6 +165,7 @@ func (s *Remote) Fetch(path string, requestBody adapter.FetchRequestBody) (body
 		return &adapter.FetchResponseBody{
 			NotModified: true,
 			LastUpdated: requestBody.LastUpdated,
+			Skipped:     true,
 		}, nil
 	}
 	request, err := http.NewRequestWithContext(s.ctx, http.MethodGet, path, nil)